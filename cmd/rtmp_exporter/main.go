@@ -1,54 +1,223 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
-	"github.com/cortexproject/cortex/pkg/util"
+	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rfratto/rtmp_exporter/exporter"
+	"github.com/rfratto/rtmp_exporter/rtmpstats"
+	"github.com/rfratto/rtmp_exporter/statspb"
 	"github.com/weaveworks/common/logging"
+	"google.golang.org/grpc"
 )
 
 func main() {
 	var (
-		cfg        exporter.Config
-		listenPort int
-		logLevel   logging.Level
+		cfg               exporter.Config
+		listenPort        int
+		grpcListenAddress string
+		mutatorsFile      string
+		shutdownTimeout   time.Duration
+		logLevel          logging.Level
+		logFormat         string
 	)
 
 	fs := flag.NewFlagSet("rtmp_exporter", flag.ExitOnError)
-	fs.IntVar(&listenPort, "listen-port", 8080, "port to listen on to expose /metrics")
+	fs.IntVar(&listenPort, "listen-port", 8080, "port to listen on to expose /metrics. If not set, the PORT environment variable is used when present")
+	fs.StringVar(&grpcListenAddress, "grpc-listen-address", "", "address to serve the stats over gRPC on. gRPC is disabled if not set")
+	fs.StringVar(&mutatorsFile, "mutators.file", "", "YAML file of rules for mutating stats before they're exposed")
+	fs.DurationVar(&shutdownTimeout, "shutdown-timeout", 30*time.Second, "maximum time to wait for in-flight requests to finish when shutting down")
+	fs.StringVar(&logFormat, "log.format", "logfmt", "Output format of log messages. Valid formats: [logfmt, json]")
 	logLevel.RegisterFlags(fs)
 	cfg.RegisterFlagsWithPrefix("", fs)
 
-	logger, err := util.NewPrometheusLogger(logLevel)
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to parse flags: %s\n", err)
+		os.Exit(1)
+	}
+
+	logger, err := newLogger(os.Stderr, logLevel, logFormat)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "failed to initialize logger: %s", err)
+		fmt.Fprintf(os.Stderr, "failed to initialize logger: %s\n", err)
 		os.Exit(1)
 	}
 
-	if err := fs.Parse(os.Args[1:]); err != nil {
-		level.Error(logger).Log("msg", "failed to parse flags", "err", err)
+	// An explicitly set -listen-port always takes precedence over $PORT.
+	if !flagSet(fs, "listen-port") {
+		if port, ok := os.LookupEnv("PORT"); ok {
+			listenPort, err = strconv.Atoi(port)
+			if err != nil || listenPort < 0 || listenPort > 65535 {
+				level.Error(logger).Log("msg", "invalid PORT environment variable", "port", port)
+				os.Exit(1)
+			}
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		level.Error(logger).Log("msg", "invalid configuration", "err", err)
 		os.Exit(1)
 	}
 
+	var mutators []rtmpstats.NamedMutator
+	if mutatorsFile != "" {
+		mutators, err = rtmpstats.LoadRules(mutatorsFile)
+		if err != nil {
+			level.Error(logger).Log("msg", "invalid mutators file", "err", err)
+			os.Exit(1)
+		}
+	}
+
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", listenPort))
 	if err != nil {
 		level.Error(logger).Log("msg", "failed to create listener", "err", err)
 		os.Exit(1)
 	}
 
-	prometheus.MustRegister(exporter.New(cfg, logger))
+	e := exporter.NewWithNamedMutators(cfg, logger, mutators)
+
+	if cfg.GraphiteAddress != "" {
+		bridge, err := e.GraphiteBridge()
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to create Graphite bridge", "err", err)
+			os.Exit(1)
+		}
+		go bridge.Run(context.Background())
+	}
+
+	if grpcListenAddress != "" {
+		grpcLis, err := net.Listen("tcp", grpcListenAddress)
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to create gRPC listener", "err", err)
+			os.Exit(1)
+		}
+
+		srv := grpc.NewServer()
+		statspb.RegisterStatsServiceServer(srv, statspb.NewServer(e.Stats))
+		go func() {
+			level.Info(logger).Log("msg", "gRPC server listening", "addr", grpcListenAddress)
+			if err := srv.Serve(grpcLis); err != nil {
+				level.Error(logger).Log("msg", "gRPC serving failed", "err", err)
+				os.Exit(1)
+			}
+		}()
+	}
+
+	srv := &http.Server{Handler: newMux(e), ConnState: e.ConnState}
+
+	// Drain in-flight requests on SIGTERM or interrupt so that scrapes aren't
+	// cut off during rollouts.
+	shutdown := make(chan struct{})
+	go func() {
+		defer close(shutdown)
+
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, syscall.SIGTERM, os.Interrupt)
+		<-sigs
+
+		level.Info(logger).Log("msg", "shutting down", "timeout", shutdownTimeout)
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			level.Warn(logger).Log("msg", "failed to drain connections", "err", err)
+		}
+	}()
 
 	level.Info(logger).Log("msg", "server listening on port", "port", listenPort)
-	if err := http.Serve(lis, promhttp.Handler()); err != nil {
+	if err := srv.Serve(lis); err != http.ErrServerClosed {
 		level.Error(logger).Log("msg", "serving failed", "err", err)
 		os.Exit(1)
 	}
+	<-shutdown
+}
+
+// newMux creates the HTTP handler serving metrics of e along with those of the
+// default registry, the metrics of single applications under /metrics/, and
+// the readiness endpoint of e. Metrics responses are gzip-compressed when
+// requested by the client.
+func newMux(e *exporter.Exporter) *http.ServeMux {
+	metrics := promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer, e.MetricsHandler(prometheus.DefaultGatherer))
+
+	mux := http.NewServeMux()
+	mux.Handle("/", metrics)
+	// /metrics must be registered explicitly, otherwise the mux redirects it
+	// to /metrics/.
+	mux.Handle("/metrics", metrics)
+	mux.Handle("/metrics/", e.ApplicationHandler())
+	mux.Handle("/-/ready", e.ReadyHandler())
+	return mux
+}
+
+// flagSet returns true if the flag with the given name was explicitly set.
+func flagSet(fs *flag.FlagSet, name string) bool {
+	var found bool
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			found = true
+		}
+	})
+	return found
+}
+
+// logMessages counts the log messages written at each level, whether or not
+// they pass the level filter.
+var logMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "log_messages_total",
+	Help: "Total number of log messages.",
+}, []string{"level"})
+
+func init() {
+	for _, lvl := range []level.Value{level.DebugValue(), level.InfoValue(), level.WarnValue(), level.ErrorValue()} {
+		logMessages.WithLabelValues(lvl.String())
+	}
+}
+
+// newLogger creates a logger filtered by lvl that writes log lines to w in the
+// given format. Both formats are wrapped the same way so that they emit the
+// same fields.
+func newLogger(w io.Writer, lvl logging.Level, format string) (log.Logger, error) {
+	var logger log.Logger
+	switch format {
+	case "logfmt":
+		logger = log.NewLogfmtLogger(log.NewSyncWriter(w))
+	case "json":
+		logger = log.NewJSONLogger(log.NewSyncWriter(w))
+	default:
+		return nil, fmt.Errorf("unrecognized log format %q", format)
+	}
+
+	logger = countingLogger{next: level.NewFilter(logger, lvl.Gokit)}
+	return log.With(logger, "ts", log.DefaultTimestampUTC, "caller", log.DefaultCaller), nil
+}
+
+// countingLogger counts the messages logged through it in logMessages by
+// their level.
+type countingLogger struct {
+	next log.Logger
+}
+
+func (l countingLogger) Log(keyvals ...interface{}) error {
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		if keyvals[i] != level.Key() {
+			continue
+		}
+		if lvl, ok := keyvals[i+1].(level.Value); ok {
+			logMessages.WithLabelValues(lvl.String()).Inc()
+		}
+		break
+	}
+	return l.next.Log(keyvals...)
 }
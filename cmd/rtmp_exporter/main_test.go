@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/rfratto/rtmp_exporter/exporter"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/logging"
+)
+
+func TestMux_Gzip(t *testing.T) {
+	e := exporter.New(exporter.Config{StatsFiles: exporter.StringSlice{"../../exporter/testdata/stats.xml"}}, log.NewNopLogger())
+	srv := httptest.NewServer(newMux(e))
+	defer srv.Close()
+
+	req, err := http.NewRequest("GET", srv.URL+"/metrics", nil)
+	require.NoError(t, err)
+	// Setting Accept-Encoding explicitly stops the transport from
+	// transparently decompressing the response.
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+
+	gz, err := gzip.NewReader(resp.Body)
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(gz)
+	require.NoError(t, err)
+	require.Contains(t, string(body), "rtmp_stream_bitrate_in{")
+}
+
+func TestNewLogger_Formats(t *testing.T) {
+	var lvl logging.Level
+	require.NoError(t, lvl.Set("info"))
+
+	keys := func(format string) []string {
+		var buf bytes.Buffer
+		logger, err := newLogger(&buf, lvl, format)
+		require.NoError(t, err)
+		level.Info(logger).Log("msg", "hello", "port", 8080)
+
+		var res []string
+		switch format {
+		case "logfmt":
+			for _, field := range strings.Fields(buf.String()) {
+				res = append(res, field[:strings.Index(field, "=")])
+			}
+		case "json":
+			var line map[string]interface{}
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+			for key := range line {
+				res = append(res, key)
+			}
+		}
+		sort.Strings(res)
+		return res
+	}
+
+	expect := []string{"caller", "level", "msg", "port", "ts"}
+	require.Equal(t, expect, keys("logfmt"))
+	require.Equal(t, expect, keys("json"))
+
+	_, err := newLogger(ioutil.Discard, lvl, "xml")
+	require.Error(t, err)
+}
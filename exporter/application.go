@@ -0,0 +1,138 @@
+package exporter
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/rfratto/rtmp_exporter/rtmpstats"
+)
+
+// ApplicationHandler returns an http.Handler serving the metrics of a single
+// application, named by the last element of the request path (e.g.,
+// /metrics/live). Only metrics labeled with the application are served; server
+// and exporter metrics are omitted. With multiple StatsURLs, metrics carry the
+// instance label of their endpoint like they do for Collect, and endpoints
+// that fail are left out. Unknown applications result in a 404.
+//
+// Requests don't use or update the state Collect keeps between scrapes, so
+// metrics that depend on previous scrapes, like smoothed bitrates, are
+// computed as if on a first scrape.
+func (e *Exporter) ApplicationHandler() http.Handler {
+	srcs := e.sources()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		if name == "" {
+			http.NotFound(w, r)
+			return
+		}
+
+		stats := make([]*rtmpstats.Stats, len(srcs))
+		errs := make([]error, len(srcs))
+
+		var wg sync.WaitGroup
+		for i, src := range srcs {
+			wg.Add(1)
+			go func(i int, src *Exporter) {
+				defer wg.Done()
+				stats[i], errs[i] = src.Stats(r.Context())
+			}(i, src)
+		}
+		wg.Wait()
+
+		var failed, found int
+		for i, err := range errs {
+			if err != nil {
+				level.Error(srcs[i].logger).Log("msg", "failed to get stats", "err", err)
+				failed++
+				continue
+			}
+
+			var ok bool
+			if stats[i], ok = filterApplication(stats[i], name); ok {
+				found++
+			}
+		}
+		if failed == len(srcs) {
+			http.Error(w, "failed to get stats", http.StatusServiceUnavailable)
+			return
+		}
+		if found == 0 {
+			http.NotFound(w, r)
+			return
+		}
+
+		reg := prometheus.NewRegistry()
+		if err := reg.Register(applicationCollector{srcs: srcs, stats: stats}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		gatherer := prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+			mfs, err := reg.Gather()
+			return applicationFamilies(mfs), err
+		})
+		promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	})
+}
+
+// applicationCollector is a prometheus.Collector that delivers the metrics
+// for the stats of each source, skipping sources without stats. The state
+// retained between scrapes is neither used nor updated.
+type applicationCollector struct {
+	srcs  []*Exporter
+	stats []*rtmpstats.Stats
+}
+
+// Describe implements prometheus.Collector. No descriptors are sent, making
+// applicationCollector an unchecked collector.
+func (c applicationCollector) Describe(ch chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector.
+func (c applicationCollector) Collect(ch chan<- prometheus.Metric) {
+	for i, src := range c.srcs {
+		if c.stats[i] != nil {
+			src.collectStatsFrom(ch, c.stats[i], scrapeState{})
+		}
+	}
+}
+
+// filterApplication returns a copy of s holding only the application with the
+// given name. ok is false if s has no such application.
+func filterApplication(s *rtmpstats.Stats, name string) (filtered *rtmpstats.Stats, ok bool) {
+	for _, app := range s.Applications {
+		if app.Name == name {
+			res := *s
+			res.Applications = []rtmpstats.Application{app}
+			return &res, true
+		}
+	}
+	return nil, false
+}
+
+// applicationFamilies returns the metrics from mfs that have an application
+// label, dropping families left empty.
+func applicationFamilies(mfs []*dto.MetricFamily) []*dto.MetricFamily {
+	res := make([]*dto.MetricFamily, 0, len(mfs))
+	for _, mf := range mfs {
+		var metrics []*dto.Metric
+		for _, m := range mf.Metric {
+			for _, l := range m.Label {
+				if l.GetName() == "application" {
+					metrics = append(metrics, m)
+					break
+				}
+			}
+		}
+		if len(metrics) > 0 {
+			mf.Metric = metrics
+			res = append(res, mf)
+		}
+	}
+	return res
+}
@@ -0,0 +1,97 @@
+package exporter
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplicationHandler(t *testing.T) {
+	e := New(Config{StatsFiles: StringSlice{"testdata/stats_apps.xml"}}, log.NewNopLogger())
+
+	t.Run("known application", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		e.ApplicationHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics/tenant-a", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var parser expfmt.TextParser
+		mfs, err := parser.TextToMetricFamilies(rec.Body)
+		require.NoError(t, err)
+
+		require.Contains(t, mfs, "rtmp_stream_bitrate_in")
+		require.NotContains(t, mfs, "rtmp_server_bitrate_in")
+		for name, mf := range mfs {
+			for _, m := range mf.Metric {
+				require.Equal(t, "tenant-a", labelValue(m, "application"), name)
+			}
+		}
+		require.Len(t, mfs["rtmp_stream_bitrate_in"].Metric, 2)
+	})
+
+	t.Run("unknown application", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		e.ApplicationHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics/missing", nil))
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("state", func(t *testing.T) {
+		e := New(Config{StatsFiles: StringSlice{"testdata/stats_apps.xml"}, BitrateWindow: 5}, log.NewNopLogger())
+
+		rec := httptest.NewRecorder()
+		e.ApplicationHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics/tenant-a", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Contains(t, rec.Body.String(), "rtmp_stream_bitrate_in_stddev{")
+		require.Empty(t, e.state.bitrateWindows)
+	})
+}
+
+func TestApplicationHandler_MultipleURLs(t *testing.T) {
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/stats_apps.xml")
+	}))
+	defer good.Close()
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "oops", http.StatusInternalServerError)
+	}))
+	defer bad.Close()
+
+	serve := func(urls ...string) *httptest.ResponseRecorder {
+		var eps Endpoints
+		for i, u := range urls {
+			eps = append(eps, Endpoint{Name: fmt.Sprintf("edge-%d", i+1), URL: u})
+		}
+		e := New(Config{StatsURLs: eps, Timeout: time.Second}, log.NewNopLogger())
+
+		rec := httptest.NewRecorder()
+		e.ApplicationHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics/tenant-a", nil))
+		return rec
+	}
+	instances := func(rec *httptest.ResponseRecorder) map[string]int {
+		var parser expfmt.TextParser
+		mfs, err := parser.TextToMetricFamilies(rec.Body)
+		require.NoError(t, err)
+
+		res := make(map[string]int)
+		for _, m := range mfs["rtmp_stream_bitrate_in"].Metric {
+			res[labelValue(m, "instance")]++
+		}
+		return res
+	}
+
+	rec := serve(good.URL, good.URL)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, map[string]int{"edge-1": 2, "edge-2": 2}, instances(rec))
+
+	rec = serve(good.URL, bad.URL)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, map[string]int{"edge-1": 2}, instances(rec))
+
+	rec = serve(bad.URL, bad.URL)
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
@@ -0,0 +1,155 @@
+package exporter
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/proxy"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// OAuth2Config configures the OAuth2 client credentials flow used to
+// authenticate requests for the stats URL.
+type OAuth2Config struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       Strings
+}
+
+func (c *OAuth2Config) RegisterFlagsWithPrefix(prefix string, fs *flag.FlagSet) {
+	fs.StringVar(&c.TokenURL, prefix+"token-url", "", "URL of the OAuth2 token endpoint used to authenticate requests for the stats URL. OAuth2 is disabled if not set")
+	fs.StringVar(&c.ClientID, prefix+"client-id", "", "OAuth2 client ID")
+	fs.StringVar(&c.ClientSecret, prefix+"client-secret", "", "OAuth2 client secret")
+	fs.Var(&c.Scopes, prefix+"scopes", "comma-separated list of OAuth2 scopes to request")
+}
+
+// Validate returns an error if the OAuth2Config is invalid.
+func (c *OAuth2Config) Validate() error {
+	if c.TokenURL == "" {
+		if c.ClientID != "" || c.ClientSecret != "" || len(c.Scopes) > 0 {
+			return fmt.Errorf("OAuth2 token URL must be set when OAuth2 is configured")
+		}
+		return nil
+	}
+	if u, err := url.Parse(c.TokenURL); err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid OAuth2 token URL %q", c.TokenURL)
+	}
+	if c.ClientID == "" {
+		return fmt.Errorf("OAuth2 client ID must be set")
+	}
+	return nil
+}
+
+// newStatsClient creates the HTTP client used to retrieve stats from a URL.
+func newStatsClient(cfg Config) (*http.Client, error) {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		addr = cfg.StatsHostOverride.Rewrite(addr)
+		if cfg.SocksProxy == "" {
+			return dialer.DialContext(ctx, network, addr)
+		}
+
+		socks, err := proxy.SOCKS5("tcp", cfg.SocksProxy, nil, dialer)
+		if err != nil {
+			return nil, fmt.Errorf("creating SOCKS5 dialer: %w", err)
+		}
+		return socks.(proxy.ContextDialer).DialContext(ctx, network, addr)
+	}
+
+	if cfg.StatsServerName != "" || cfg.StatsCAFile != "" {
+		tlsConfig := &tls.Config{ServerName: cfg.StatsServerName}
+		if cfg.StatsCAFile != "" {
+			pool, err := loadCertPool(cfg.StatsCAFile)
+			if err != nil {
+				return nil, err
+			}
+			tlsConfig.RootCAs = pool
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	client := &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > cfg.MaxRedirects {
+				return fmt.Errorf("stopped after %d redirects", cfg.MaxRedirects)
+			}
+			// The OAuth2 transport attaches the token to every request,
+			// so following a redirect to another host would hand it the
+			// token.
+			if cfg.OAuth2.TokenURL != "" && req.URL.Host != via[0].URL.Host {
+				return fmt.Errorf("refusing redirect to %s with OAuth2 credentials", req.URL.Host)
+			}
+			return nil
+		},
+	}
+
+	if cfg.OAuth2.TokenURL != "" {
+		ccfg := clientcredentials.Config{
+			ClientID:     cfg.OAuth2.ClientID,
+			ClientSecret: cfg.OAuth2.ClientSecret,
+			TokenURL:     cfg.OAuth2.TokenURL,
+			Scopes:       cfg.OAuth2.Scopes,
+		}
+
+		// Tokens are requested through the same transport so the token URL
+		// also honors the proxy and TLS settings. The token source caches
+		// tokens and refreshes them once they expire.
+		ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{
+			Transport: transport,
+			Timeout:   cfg.Timeout,
+		})
+		client.Transport = &oauth2.Transport{
+			Source: ccfg.TokenSource(ctx),
+			Base:   transport,
+		}
+	}
+
+	return client, nil
+}
+
+// loadCertPool creates a certificate pool from the PEM-encoded certificates
+// in the given file.
+func loadCertPool(path string) (*x509.CertPool, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading CA file: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(buf) {
+		return nil, fmt.Errorf("no certificates found in CA file %q", path)
+	}
+	return pool, nil
+}
+
+// tlsVersionName returns a human-readable name for a TLS version.
+func tlsVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	default:
+		return fmt.Sprintf("0x%04X", version)
+	}
+}
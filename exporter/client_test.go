@@ -0,0 +1,368 @@
+package exporter
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsClient_HostOverride(t *testing.T) {
+	var host string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host = r.Host
+		http.ServeFile(w, r, "testdata/stats.xml")
+	}))
+	defer srv.Close()
+
+	_, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	require.NoError(t, err)
+
+	cfg := Config{
+		StatsURL: "http://stats.invalid:" + port + "/stat",
+		Timeout:  time.Second,
+	}
+	require.NoError(t, cfg.StatsHostOverride.Set("stats.invalid:127.0.0.1"))
+
+	_, err = New(cfg, log.NewNopLogger()).Stats(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "stats.invalid:"+port, host)
+}
+
+func TestStatsClient_SocksProxy(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/stats.xml")
+	}))
+	defer srv.Close()
+
+	_, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	require.NoError(t, err)
+
+	socks := newSocksServer(t)
+	defer socks.Close()
+
+	cfg := Config{
+		StatsURL:   "http://stats.invalid:" + port + "/stat",
+		Timeout:    time.Second,
+		SocksProxy: socks.Addr().String(),
+	}
+	require.NoError(t, cfg.StatsHostOverride.Set("stats.invalid:127.0.0.1"))
+	require.NoError(t, cfg.Validate())
+
+	_, err = New(cfg, log.NewNopLogger()).Stats(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{"127.0.0.1:" + port}, socks.Targets())
+}
+
+func TestStatsClient_ServerName(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/stats.xml")
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "rtmp_exporter")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// The test server's certificate is only valid for example.com and
+	// loopback addresses.
+	caFile := filepath.Join(dir, "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	require.NoError(t, ioutil.WriteFile(caFile, caPEM, 0644))
+
+	_, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	require.NoError(t, err)
+
+	tt := []struct {
+		name       string
+		serverName string
+		expectErr  bool
+	}{
+		{name: "valid", serverName: "example.com"},
+		{name: "invalid", serverName: "stats.invalid", expectErr: true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := Config{
+				StatsURL:        "https://stats.invalid:" + port + "/stat",
+				Timeout:         time.Second,
+				StatsServerName: tc.serverName,
+				StatsCAFile:     caFile,
+			}
+			require.NoError(t, cfg.StatsHostOverride.Set("stats.invalid:127.0.0.1"))
+			require.NoError(t, cfg.Validate())
+
+			_, err := New(cfg, log.NewNopLogger()).Stats(context.Background())
+			if tc.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestStatsClient_TLSVersionInfo(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/stats.xml")
+	}))
+	srv.TLS = &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		MaxVersion:   tls.VersionTLS12,
+		CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+	}
+	srv.StartTLS()
+	defer srv.Close()
+
+	e := New(Config{StatsURL: srv.URL, Timeout: time.Second}, log.NewNopLogger())
+	e.client = srv.Client()
+
+	mfs := gather(t, e)
+	mf := findFamily(t, mfs, "rtmp_stats_tls_version_info")
+	require.Len(t, mf.Metric, 1)
+	require.Equal(t, "TLS 1.2", labelValue(mf.Metric[0], "version"))
+	require.Equal(t, "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", labelValue(mf.Metric[0], "cipher"))
+
+	// The metric is only exposed for TLS connections.
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/stats.xml")
+	}))
+	defer plain.Close()
+
+	mfs = gather(t, New(Config{StatsURL: plain.URL, Timeout: time.Second}, log.NewNopLogger()))
+	for _, mf := range mfs {
+		require.NotEqual(t, "rtmp_stats_tls_version_info", mf.GetName())
+	}
+}
+
+func TestStatsClient_MaxRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/old", http.RedirectHandler("/stat", http.StatusMovedPermanently))
+	mux.Handle("/loop", http.RedirectHandler("/loop", http.StatusFound))
+	mux.HandleFunc("/stat", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/stats.xml")
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tt := []struct {
+		name         string
+		path         string
+		maxRedirects int
+		expectErr    string
+	}{
+		{name: "redirect", path: "/old", maxRedirects: 1},
+		{name: "redirects disabled", path: "/old", maxRedirects: 0, expectErr: "stopped after 0 redirects"},
+		{name: "loop", path: "/loop", maxRedirects: 3, expectErr: "stopped after 3 redirects"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := Config{StatsURL: srv.URL + tc.path, Timeout: time.Second, MaxRedirects: tc.maxRedirects}
+			_, err := New(cfg, log.NewNopLogger()).Stats(context.Background())
+			if tc.expectErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.expectErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestStatsClient_OAuth2(t *testing.T) {
+	var tokenRequests int
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenRequests++
+		if id, secret, _ := r.BasicAuth(); id != "exporter" || secret != "secret" || r.FormValue("scope") != "stats" {
+			http.Error(w, "invalid client", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"access_token":"fake-token","token_type":"bearer","expires_in":3600}`)
+	}))
+	defer tokenSrv.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer fake-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		http.ServeFile(w, r, "testdata/stats.xml")
+	}))
+	defer srv.Close()
+
+	cfg := Config{
+		StatsURL: srv.URL + "/stat",
+		Timeout:  time.Second,
+		OAuth2: OAuth2Config{
+			TokenURL:     tokenSrv.URL,
+			ClientID:     "exporter",
+			ClientSecret: "secret",
+			Scopes:       Strings{"stats"},
+		},
+	}
+	require.NoError(t, cfg.Validate())
+
+	e := New(cfg, log.NewNopLogger())
+	for i := 0; i < 2; i++ {
+		_, err := e.Stats(context.Background())
+		require.NoError(t, err)
+	}
+	require.Equal(t, 1, tokenRequests, "token should be reused until it expires")
+}
+
+func TestStatsClient_OAuth2_Redirect(t *testing.T) {
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"access_token":"fake-token","token_type":"bearer","expires_in":3600}`)
+	}))
+	defer tokenSrv.Close()
+
+	var leaked bool
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		leaked = r.Header.Get("Authorization") != ""
+		http.ServeFile(w, r, "testdata/stats.xml")
+	}))
+	defer other.Close()
+
+	mux := http.NewServeMux()
+	mux.Handle("/old", http.RedirectHandler("/stat", http.StatusMovedPermanently))
+	mux.Handle("/moved", http.RedirectHandler(other.URL+"/stat", http.StatusMovedPermanently))
+	mux.HandleFunc("/stat", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/stats.xml")
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	stats := func(path string) error {
+		cfg := Config{
+			StatsURL:     srv.URL + path,
+			Timeout:      time.Second,
+			MaxRedirects: 3,
+			OAuth2:       OAuth2Config{TokenURL: tokenSrv.URL, ClientID: "exporter"},
+		}
+		_, err := New(cfg, log.NewNopLogger()).Stats(context.Background())
+		return err
+	}
+
+	require.NoError(t, stats("/old"))
+
+	err := stats("/moved")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "refusing redirect")
+	require.False(t, leaked, "token should not be sent to another host")
+}
+
+func TestConfig_Validate(t *testing.T) {
+	cfg := Config{SocksProxy: "localhost"}
+	require.Error(t, cfg.Validate())
+
+	cfg = Config{StatsCAFile: "testdata/stats.xml"}
+	require.Error(t, cfg.Validate())
+
+	cfg = Config{MaxRedirects: -1}
+	require.Error(t, cfg.Validate())
+
+	cfg = Config{StatsCommand: "rtmp-exporter-missing-command"}
+	require.Error(t, cfg.Validate())
+
+	cfg = Config{StatsCommand: "cat testdata/stats.xml"}
+	require.NoError(t, cfg.Validate())
+
+	cfg = Config{OAuth2: OAuth2Config{ClientID: "exporter"}}
+	require.Error(t, cfg.Validate())
+
+	cfg = Config{OAuth2: OAuth2Config{TokenURL: "http://localhost/token"}}
+	require.Error(t, cfg.Validate())
+
+	cfg = Config{OAuth2: OAuth2Config{TokenURL: "http://localhost/token", ClientID: "exporter"}}
+	require.NoError(t, cfg.Validate())
+}
+
+// socksServer is a minimal SOCKS5 server supporting unauthenticated CONNECT
+// requests to IPv4 addresses.
+type socksServer struct {
+	net.Listener
+
+	mut     sync.Mutex
+	targets []string
+}
+
+func newSocksServer(t *testing.T) *socksServer {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &socksServer{Listener: lis}
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go s.handle(conn)
+		}
+	}()
+	return s
+}
+
+func (s *socksServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	// Greeting: version, number of methods, methods.
+	greeting := make([]byte, 2)
+	if _, err := io.ReadFull(conn, greeting); err != nil {
+		return
+	}
+	if _, err := io.ReadFull(conn, make([]byte, greeting[1])); err != nil {
+		return
+	}
+	if _, err := conn.Write([]byte{0x05, 0x00}); err != nil {
+		return
+	}
+
+	// Request: version, command, reserved, address type, IPv4, port.
+	req := make([]byte, 10)
+	if _, err := io.ReadFull(conn, req); err != nil || req[3] != 0x01 {
+		return
+	}
+	target := net.JoinHostPort(net.IP(req[4:8]).String(), strconv.Itoa(int(binary.BigEndian.Uint16(req[8:10]))))
+
+	s.mut.Lock()
+	s.targets = append(s.targets, target)
+	s.mut.Unlock()
+
+	upstream, err := net.Dial("tcp", target)
+	if err != nil {
+		return
+	}
+	defer upstream.Close()
+
+	if _, err := conn.Write([]byte{0x05, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0, 0}); err != nil {
+		return
+	}
+
+	go func() { _, _ = io.Copy(upstream, conn) }()
+	_, _ = io.Copy(conn, upstream)
+}
+
+func (s *socksServer) Targets() []string {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.targets
+}
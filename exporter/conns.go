@@ -0,0 +1,18 @@
+package exporter
+
+import (
+	"net"
+	"net/http"
+)
+
+// ConnState tracks the number of open connections to the HTTP server serving
+// the exporter, exposed as rtmp_exporter_active_connections. It should be
+// used as the ConnState callback of the http.Server.
+func (e *Exporter) ConnState(conn net.Conn, state http.ConnState) {
+	switch state {
+	case http.StateNew:
+		e.activeConnections.Inc()
+	case http.StateClosed, http.StateHijacked:
+		e.activeConnections.Dec()
+	}
+}
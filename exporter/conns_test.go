@@ -0,0 +1,37 @@
+package exporter
+
+import (
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExporter_ConnState(t *testing.T) {
+	e := New(Config{StatsFiles: StringSlice{"testdata/stats.xml"}}, log.NewNopLogger())
+
+	active := func() float64 {
+		mf := findFamily(t, gather(t, e), "rtmp_exporter_active_connections")
+		return mf.Metric[0].GetGauge().GetValue()
+	}
+
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+
+	e.ConnState(a, http.StateNew)
+	e.ConnState(b, http.StateNew)
+	require.Equal(t, float64(2), active())
+
+	e.ConnState(a, http.StateActive)
+	e.ConnState(a, http.StateIdle)
+	require.Equal(t, float64(2), active())
+
+	e.ConnState(a, http.StateClosed)
+	require.Equal(t, float64(1), active())
+
+	e.ConnState(b, http.StateHijacked)
+	require.Equal(t, float64(0), active())
+}
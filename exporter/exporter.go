@@ -3,36 +3,487 @@
 package exporter
 
 import (
+	"bytes"
+	"crypto/tls"
+	"encoding/xml"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
 	"net/http"
 	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
 	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
 	"github.com/rfratto/rtmp_exporter/rtmpstats"
 	"golang.org/x/net/context"
 )
 
 type Config struct {
-	StatsURL  string
-	StatsFile string
-	Timeout   time.Duration
+	StatsURL   string
+	StatsFiles StringSlice
+	Timeout    time.Duration
+
+	// StatsURLs are endpoints to get the stats from, overriding StatsURL. A
+	// single endpoint is used as StatsURL. Multiple endpoints are scraped
+	// concurrently and independently, with an instance label holding the
+	// name of the endpoint added to the metrics derived from their stats.
+	// Exporter metrics like scrape counts are exposed once, without the
+	// instance label.
+	StatsURLs Endpoints
+
+	// StatsCommand is a command whose stdout is read as the stats document.
+	// Arguments are separated by whitespace; no shell expansion is performed.
+	StatsCommand string
+
+	// FileTimeout bounds reading StatsFiles. Timeout is used when unset.
+	// Reading files isn't bounded when neither is set.
+	FileTimeout time.Duration
+
+	// StatsHostOverride resolves the host of StatsURL to a fixed IP address
+	// instead of using DNS.
+	StatsHostOverride HostOverride
+
+	// SocksProxy is the host:port of a SOCKS5 proxy to connect to StatsURL
+	// through.
+	SocksProxy string
+
+	// StatsServerName overrides the server name used for verifying the
+	// certificate of StatsURL and for SNI. Useful when StatsURL addresses the
+	// server by IP.
+	StatsServerName string
+
+	// MaxRedirects is the maximum number of redirects followed when
+	// retrieving StatsURL. Redirects are not followed when zero. When OAuth2 is
+	// configured, redirects to another host are refused.
+	MaxRedirects int
+
+	// StatsCAFile is a PEM file of CA certificates used to verify the
+	// certificate of StatsURL instead of the system roots.
+	StatsCAFile string
+
+	// OAuth2 configures retrieving StatsURL with a bearer token obtained
+	// through the OAuth2 client credentials flow. Disabled when TokenURL is
+	// empty.
+	OAuth2 OAuth2Config
+
+	// ClientUptimeBuckets are the histogram buckets used for the per-stream
+	// client uptime distribution.
+	ClientUptimeBuckets Buckets
+
+	// DropPublisherLabel removes the publisher label, holding the ID of the
+	// publishing client, from all per-stream metrics. Streams with multiple
+	// publishers expose per-stream metrics once for each publisher unless the
+	// label is dropped, in which case only the first publisher is used.
+	DropPublisherLabel bool
+
+	// AVSyncThresholdMs is the absolute A-V sync drift in milliseconds above
+	// which a client is considered out of sync.
+	AVSyncThresholdMs int
+
+	// RelayFlashVersionPrefixes are the flash version prefixes which identify a
+	// publishing client as a relay from another server.
+	RelayFlashVersionPrefixes Strings
+
+	// MultiDocument treats the stats as a sequence of concatenated documents
+	// (e.g., one per nginx worker) that are merged together.
+	MultiDocument bool
+
+	// UseDataTimestamp timestamps the metrics derived from StatsFiles with the
+	// latest modification time of the files rather than the scrape time.
+	// Exporter metrics like scrape counts are unaffected. Stats from URLs and
+	// commands carry no timestamp and are always exposed at scrape time.
+	//
+	// Prometheus doesn't mark timestamped series as stale, so series of a
+	// stream that disappears are still returned by queries for up to five
+	// minutes after their last sample. Samples more than about an hour older
+	// than the newest data in Prometheus are rejected as out of bounds.
+	UseDataTimestamp bool
+
+	// IngestOnly limits metrics to applications that have at least one
+	// publishing stream. It is applied in addition to ApplicationAllowlist.
+	IngestOnly bool
+
+	// ConstantLabels are added to every metric exposed by the exporter. Label
+	// names must not conflict with the labels of any metric.
+	ConstantLabels Labels
+
+	// ApplicationAllowlist limits metrics to applications whose name fully
+	// matches one of the patterns. All applications are exposed when empty.
+	ApplicationAllowlist Patterns
+
+	// DetectStuckClients exposes the number of clients per active stream
+	// whose timestamp hasn't advanced since the previous scrape. The last seen
+	// timestamp of every client is retained between scrapes.
+	DetectStuckClients bool
+
+	// UptimeResolution is the resolution that stream and client uptimes are
+	// rounded down to before being exposed. Uptimes are exposed at full
+	// precision when zero.
+	UptimeResolution time.Duration
+
+	// UnifiedByteMetrics exposes server-wide byte counts as a single
+	// rtmp_bytes_total metric with a direction label, replacing
+	// rtmp_server_bytes_read_total and rtmp_server_bytes_sent_total.
+	UnifiedByteMetrics bool
+
+	// IgnoreMutatorErrors logs and skips mutators that fail instead of
+	// failing the scrape. Changes made by a mutator before it failed are
+	// kept.
+	IgnoreMutatorErrors bool
+
+	// PIDLabel adds the nginx PID as a pid label to the server byte counters
+	// so that an nginx restart, which resets the counters, starts a new
+	// series. This creates new series on every restart.
+	PIDLabel bool
+
+	// StartupGracePeriod is the amount of time after startup during which the
+	// readiness handler reports ready even if no scrape has succeeded yet.
+	StartupGracePeriod time.Duration
+
+	// FileRetryDelay is the delay before retrying once when a stats file
+	// can't be parsed, such as when it's read while being rewritten. Files
+	// aren't retried when zero.
+	FileRetryDelay time.Duration
+
+	// MetricNameTemplate is a text/template used to name metrics which have
+	// an application label, creating separate metric names per application.
+	// See MetricNameData for the available fields.
+	MetricNameTemplate string
+
+	// HealthExpectedStreams are patterns of application/stream names. When
+	// set, rtmp_health requires at least one matching stream to be present;
+	// otherwise any stream is sufficient.
+	HealthExpectedStreams Patterns
+
+	// HealthIgnoreMutatorErrors allows rtmp_health to report healthy when
+	// mutators were skipped due to IgnoreMutatorErrors.
+	HealthIgnoreMutatorErrors bool
+
+	// BitrateSmoothing is the smoothing factor (alpha) of an exponential
+	// moving average applied across scrapes to server and stream bitrates,
+	// exposed alongside the raw bitrates. Must be between 0 and 1, where
+	// lower values smooth more. Disabled when zero.
+	BitrateSmoothing float64
+
+	// TrackClientChurn exposes counters of viewers joining and leaving each
+	// stream, found by comparing client IDs against the previous scrape. The
+	// client IDs of every stream are retained between scrapes.
+	TrackClientChurn bool
+
+	// BitrateWindow is the number of scrapes over which the standard
+	// deviation of the incoming bitrate of each stream is computed. The most
+	// recent bitrates of every stream are retained between scrapes. Disabled
+	// when zero.
+	BitrateWindow int
+
+	// GraphiteAddress is the host:port of a Graphite server that metrics are
+	// pushed to every GraphiteInterval. Metrics aren't pushed when empty.
+	GraphiteAddress  string
+	GraphitePrefix   string
+	GraphiteInterval time.Duration
+
+	// ChangeLog logs at info level when a stream appears or disappears, or
+	// when its incoming bitrate or resolution changes between scrapes. The
+	// last seen values of every stream are retained between scrapes.
+	ChangeLog bool
+
+	// ChangeLogBitrateThreshold is the relative change of the incoming
+	// bitrate of a stream (e.g., 0.5 for 50%) above which ChangeLog logs a
+	// change.
+	ChangeLogBitrateThreshold float64
+
+	// WarnOnDuplicateStreamNames logs a warning for every stream name that is
+	// used by more than one application.
+	WarnOnDuplicateStreamNames bool
+
+	// MaxStreams and MaxClients limit the number of streams and viewers that
+	// metrics are exposed for, guarding against a stats page listing an
+	// unexpectedly large number of them. Streams past the limit are omitted
+	// from all metrics other than application-level counts and server-wide
+	// totals; viewers past the limit are omitted from per-client metrics.
+	// Unlimited when zero.
+	MaxStreams int
+	MaxClients int
+
+	// TrackObservedAge exposes the amount of time since the exporter first
+	// saw each stream, independent of the uptime reported by nginx. The time
+	// every stream was first seen is retained between scrapes.
+	TrackObservedAge bool
+
+	// ClientSubnetPrefix is the prefix length that the addresses of IPv4
+	// viewers are masked to for rtmp_clients_by_subnet. Viewers aren't grouped
+	// by subnet when zero.
+	ClientSubnetPrefix int
+
+	// ClientSubnetPrefixV6 is the prefix length that the addresses of IPv6
+	// viewers are masked to when ClientSubnetPrefix is set.
+	ClientSubnetPrefixV6 int
+
+	// LevelMaxBitrates is the maximum video bitrate allowed at each H.264
+	// level for the Baseline, Main, and Extended profiles, used to expose
+	// rtmp_stream_bitrate_vs_level_ratio. Bitrates are scaled for the High
+	// profiles as described by profileBitrateFactors. Defaults to
+	// DefaultLevelMaxBitrates when nil.
+	LevelMaxBitrates LevelBitrates
+
+	// BandwidthCapBitsOut is the outgoing bandwidth of the server in bits per
+	// second, used to expose rtmp_server_egress_utilization_ratio. The ratio
+	// isn't exposed when zero.
+	BandwidthCapBitsOut int64
+
+	// DropZeroSeries omits the per-stream metrics of idle streams, which are
+	// inactive with zero bitrates, byte counts, and clients. Only
+	// rtmp_stream_active is exposed for them.
+	DropZeroSeries bool
+
+	// ExposeClientMetrics exposes the dropped frames and A-V sync drift of
+	// every client, publishers included. These create series for every
+	// client ID and are not limited by MaxClients.
+	ExposeClientMetrics bool
 }
 
 func (c *Config) RegisterFlagsWithPrefix(prefix string, fs *flag.FlagSet) {
-	fs.StringVar(&c.StatsURL, prefix+"stats-url", "", "URL to get the nginx rtmp stats from")
-	fs.StringVar(&c.StatsFile, prefix+"stats-file", "", "File on disk to get the stats file from rather than getting it via URL")
+	fs.Var(&c.StatsURLs, prefix+"stats-url", "URL to get the nginx rtmp stats from. May be repeated to scrape multiple servers, each given as name=url or a URL named by its host, adding an instance label with the name to every metric")
+	fs.Var(&c.StatsFiles, prefix+"stats-file", "File on disk to get the stats file from rather than getting it via URL. May be repeated to merge stats from multiple files")
+	fs.StringVar(&c.StatsCommand, prefix+"stats-command", "", "command to run to get the stats from its stdout rather than getting it via URL. Arguments are separated by whitespace")
 	fs.DurationVar(&c.Timeout, prefix+"stats-timeout", time.Second*5, "timeout to retrieve rtmp stats")
+	fs.DurationVar(&c.FileTimeout, prefix+"stats-file-timeout", 0, "timeout to read the stats files. -stats-timeout is used if not set")
+	fs.Var(&c.StatsHostOverride, prefix+"stats-host-override", "host:ip pair that connects to the given IP when the stats URL uses the given host, bypassing DNS")
+	fs.StringVar(&c.SocksProxy, prefix+"stats-socks-proxy", "", "host:port of a SOCKS5 proxy to retrieve the stats URL through")
+	fs.StringVar(&c.StatsServerName, prefix+"stats-server-name", "", "server name used for SNI and verifying the certificate of the stats URL")
+	c.OAuth2.RegisterFlagsWithPrefix(prefix+"stats-oauth2-", fs)
+	fs.IntVar(&c.MaxRedirects, prefix+"stats-max-redirects", 3, "maximum number of redirects to follow when retrieving the stats URL")
+	fs.StringVar(&c.StatsCAFile, prefix+"stats-ca-file", "", "PEM file of CA certificates used to verify the certificate of the stats URL")
+
+	c.ClientUptimeBuckets = DefaultClientUptimeBuckets
+	fs.Var(&c.ClientUptimeBuckets, prefix+"client-uptime-buckets", "comma-separated list of histogram buckets (in seconds) for client uptime per stream")
+	fs.BoolVar(&c.DropPublisherLabel, prefix+"drop-publisher-label", false, "drop the publisher label holding the ID of the publishing client from per-stream metrics")
+	fs.IntVar(&c.AVSyncThresholdMs, prefix+"avsync-threshold-ms", 1000, "absolute A-V sync drift in milliseconds above which a client is considered out of sync")
+	c.RelayFlashVersionPrefixes = DefaultRelayFlashVersionPrefixes
+	fs.Var(&c.RelayFlashVersionPrefixes, prefix+"relay-flashver-prefixes", "comma-separated list of flash version prefixes that identify a publisher as a relay")
+	fs.BoolVar(&c.MultiDocument, prefix+"stats-multi-document", false, "parse the stats as multiple concatenated documents, summing server counters and merging streams")
+	fs.BoolVar(&c.UseDataTimestamp, prefix+"use-data-timestamp", false, "timestamp metrics derived from stats files with the modification time of the files instead of the scrape time")
+	fs.BoolVar(&c.IngestOnly, prefix+"ingest-only", false, "only expose metrics for applications with at least one publishing stream. Applied in addition to -application-allowlist")
+	fs.Var(&c.ConstantLabels, prefix+"constant-labels", "comma-separated list of name=value labels to add to every metric")
+	fs.Var(&c.ApplicationAllowlist, prefix+"application-allowlist", "regular expression of application names to expose metrics for. May be repeated. All applications are exposed if not set")
+	fs.BoolVar(&c.DetectStuckClients, prefix+"detect-stuck-clients", false, "expose the number of viewers per stream whose timestamp hasn't advanced since the previous scrape. Retains the last timestamp of every client between scrapes")
+	fs.DurationVar(&c.UptimeResolution, prefix+"uptime-resolution", 0, "resolution (e.g., 1s) that stream and client uptimes are rounded down to. Uptimes are exposed at full precision if not set")
+	fs.BoolVar(&c.UnifiedByteMetrics, prefix+"unified-byte-metrics", false, "expose server byte counts as rtmp_bytes_total with a direction label instead of separate read and sent metrics")
+	fs.BoolVar(&c.IgnoreMutatorErrors, prefix+"ignore-mutator-errors", false, "log and skip failing mutators instead of failing the scrape")
+	fs.BoolVar(&c.PIDLabel, prefix+"pid-label", false, "add the nginx PID as a label to server byte counters so restarts start new series. Creates new series on every restart")
+	fs.DurationVar(&c.StartupGracePeriod, prefix+"startup-grace-period", 0, "amount of time after startup during which /-/ready reports ready before the first successful scrape")
+	fs.DurationVar(&c.FileRetryDelay, prefix+"stats-file-retry-delay", 0, "delay before retrying once when a stats file appears partially written. Disabled if not set")
+	fs.StringVar(&c.MetricNameTemplate, prefix+"metric-name-template", "", "Go template for naming metrics with an application label, e.g. rtmp_{{.Application}}_{{.Name}}. Creates separate metrics per application")
+	fs.Var(&c.HealthExpectedStreams, prefix+"health-expected-streams", "regular expression of application/stream names, one of which must be present for rtmp_health to report healthy. May be repeated. Any stream is sufficient if not set")
+	fs.BoolVar(&c.HealthIgnoreMutatorErrors, prefix+"health-ignore-mutator-errors", false, "allow rtmp_health to report healthy when mutators were skipped")
+	fs.Float64Var(&c.BitrateSmoothing, prefix+"bitrate-smoothing", 0, "smoothing factor between 0 and 1 of an exponential moving average across scrapes exposed for server and stream bitrates. Lower values smooth more. Disabled if not set")
+	fs.BoolVar(&c.TrackClientChurn, prefix+"track-client-churn", false, "expose counters of viewers joining and leaving each stream. Retains the client IDs of every stream between scrapes")
+	fs.IntVar(&c.BitrateWindow, prefix+"bitrate-window", 0, "number of scrapes over which the standard deviation of the incoming bitrate of each stream is exposed. Disabled if not set")
+	fs.StringVar(&c.GraphiteAddress, prefix+"graphite-address", "", "host:port of a Graphite server to push metrics to. Metrics are not pushed if not set")
+	fs.StringVar(&c.GraphitePrefix, prefix+"graphite-prefix", "rtmp", "prefix of the metric paths pushed to Graphite")
+	fs.DurationVar(&c.GraphiteInterval, prefix+"graphite-interval", 15*time.Second, "interval to push metrics to Graphite at")
+	fs.BoolVar(&c.ChangeLog, prefix+"change-log", false, "log when streams appear, disappear, or change bitrate or resolution between scrapes")
+	fs.Float64Var(&c.ChangeLogBitrateThreshold, prefix+"change-log-bitrate-threshold", 0.5, "relative change of a stream's incoming bitrate (e.g., 0.5 for 50%) above which -change-log logs a change")
+	fs.IntVar(&c.MaxStreams, prefix+"max-streams", 0, "maximum number of streams to expose metrics for. Unlimited if not set")
+	fs.IntVar(&c.MaxClients, prefix+"max-clients", 0, "maximum number of viewers to expose per-client metrics for. Unlimited if not set")
+	fs.BoolVar(&c.TrackObservedAge, prefix+"track-observed-age", false, "expose the time since the exporter first saw each stream. Retains the first seen time of every stream between scrapes")
+	fs.IntVar(&c.ClientSubnetPrefix, prefix+"client-subnet-prefix", 0, "prefix length (e.g., 24) of the subnets that IPv4 viewers are grouped into for rtmp_clients_by_subnet. Disabled if not set")
+	fs.IntVar(&c.ClientSubnetPrefixV6, prefix+"client-subnet-prefix-v6", 48, "prefix length of the subnets that IPv6 viewers are grouped into when -client-subnet-prefix is set")
+	fs.BoolVar(&c.WarnOnDuplicateStreamNames, prefix+"warn-on-duplicate-stream-names", false, "log a warning when the same stream name is used by more than one application")
+	c.LevelMaxBitrates = DefaultLevelMaxBitrates
+	fs.Var(&c.LevelMaxBitrates, prefix+"level-max-bitrates", "comma-separated list of level=kbps pairs overriding the maximum Baseline and Main profile video bitrate of H.264 levels")
+	fs.Int64Var(&c.BandwidthCapBitsOut, prefix+"bandwidth-cap-bits-out", 0, "outgoing bandwidth of the server in bits per second, used to expose the ratio of the outgoing bitrate to it. Disabled if not set")
+	fs.BoolVar(&c.DropZeroSeries, prefix+"drop-zero-series", false, "only expose rtmp_stream_active for inactive streams without any traffic or clients, omitting their other per-stream metrics")
+	fs.BoolVar(&c.ExposeClientMetrics, prefix+"expose-client-metrics", false, "expose the dropped frames and A-V sync drift of every client, including publishers. Creates series for every client ID and ignores -max-clients")
+}
+
+// Validate returns an error if the Config is invalid.
+func (c *Config) Validate() error {
+	if c.SocksProxy != "" {
+		if _, _, err := net.SplitHostPort(c.SocksProxy); err != nil {
+			return fmt.Errorf("invalid SOCKS5 proxy address %q: %w", c.SocksProxy, err)
+		}
+	}
+	if c.StatsCommand != "" {
+		args := strings.Fields(c.StatsCommand)
+		if len(args) == 0 {
+			return fmt.Errorf("stats command is empty")
+		}
+		if _, err := exec.LookPath(args[0]); err != nil {
+			return fmt.Errorf("invalid stats command: %w", err)
+		}
+	}
+	for _, name := range variableLabelNames {
+		if _, ok := c.ConstantLabels[name]; ok {
+			return fmt.Errorf("%s constant label conflicts with the label of exposed metrics", name)
+		}
+	}
+	if len(c.StatsURLs) > 1 {
+		if _, ok := c.ConstantLabels["instance"]; ok {
+			return fmt.Errorf("instance constant label conflicts with the label of multiple stats URLs")
+		}
+		names := make(map[string]struct{}, len(c.StatsURLs))
+		for _, ep := range c.StatsURLs {
+			if _, ok := names[ep.Name]; ok {
+				return fmt.Errorf("duplicate stats URL name %q", ep.Name)
+			}
+			names[ep.Name] = struct{}{}
+		}
+	}
+	if c.MaxRedirects < 0 {
+		return fmt.Errorf("max redirects must not be negative")
+	}
+	if c.FileTimeout < 0 {
+		return fmt.Errorf("file timeout must not be negative")
+	}
+	if c.BandwidthCapBitsOut < 0 {
+		return fmt.Errorf("outgoing bandwidth cap must not be negative")
+	}
+	if c.BitrateSmoothing < 0 || c.BitrateSmoothing > 1 {
+		return fmt.Errorf("bitrate smoothing must be between 0 and 1, got %v", c.BitrateSmoothing)
+	}
+	if c.GraphiteAddress != "" {
+		if _, _, err := net.SplitHostPort(c.GraphiteAddress); err != nil {
+			return fmt.Errorf("invalid Graphite address %q: %w", c.GraphiteAddress, err)
+		}
+	}
+	if c.ChangeLogBitrateThreshold < 0 {
+		return fmt.Errorf("change log bitrate threshold must not be negative")
+	}
+	if c.MaxStreams < 0 || c.MaxClients < 0 {
+		return fmt.Errorf("max streams and max clients must not be negative")
+	}
+	if c.ClientSubnetPrefix < 0 || c.ClientSubnetPrefix > 32 {
+		return fmt.Errorf("client subnet prefix must be between 0 and 32, got %d", c.ClientSubnetPrefix)
+	}
+	if c.ClientSubnetPrefixV6 < 0 || c.ClientSubnetPrefixV6 > 128 {
+		return fmt.Errorf("IPv6 client subnet prefix must be between 0 and 128, got %d", c.ClientSubnetPrefixV6)
+	}
+	if c.BitrateWindow < 0 {
+		return fmt.Errorf("bitrate window must not be negative")
+	}
+	if c.MetricNameTemplate != "" {
+		if _, err := parseMetricNameTemplate(c.MetricNameTemplate); err != nil {
+			return err
+		}
+	}
+	if c.StatsCAFile != "" {
+		if _, err := loadCertPool(c.StatsCAFile); err != nil {
+			return err
+		}
+	}
+	if err := c.OAuth2.Validate(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DefaultClientUptimeBuckets are the default buckets used for the client
+// uptime histogram, ranging from 30 seconds to 4 hours.
+var DefaultClientUptimeBuckets = Buckets{30, 60, 300, 600, 1800, 3600, 7200, 14400}
+
+// DefaultLevelMaxBitrates are the maximum video bitrates in kbit/s of the
+// H.264 levels for the Baseline, Main, and Extended profiles, from Table A-1 of
+// the H.264 specification. Level 1b is omitted since it can't be told apart
+// from level 1.1 in stream meta information. The High profiles allow higher
+// bitrates; see profileBitrateFactors.
+var DefaultLevelMaxBitrates = LevelBitrates{
+	1: 64, 1.1: 192, 1.2: 384, 1.3: 768,
+	2: 2000, 2.1: 4000, 2.2: 4000,
+	3: 10000, 3.1: 14000, 3.2: 20000,
+	4: 20000, 4.1: 50000, 4.2: 50000,
+	5: 135000, 5.1: 240000, 5.2: 240000,
+	6: 240000, 6.1: 480000, 6.2: 800000,
+}
+
+// variableLabelNames are the names of the labels set per metric by the
+// exporter, which constant labels must not use.
+var variableLabelNames = []string{
+	"application", "stream", "publisher", "client", "client_id", "subnet", "resolution",
+	"video_resolution", "frame_rate", "video_codec", "audio_codec", "audio_channels", "audio_sample_rate",
+	"type", "index", "codec", "profile",
+	"direction", "pid", "element", "limit", "version", "cipher",
+	"nginx_version", "nginx_rtmp_version", "compiler", "built", "le",
+}
+
+// profileBitrateFactors scale the level bitrates of LevelMaxBitrates for the
+// H.264 profiles that allow higher bitrates, from Table A-2 of the H.264
+// specification (cpbBrVclFactor relative to the Baseline profile). Profiles
+// not listed use the level bitrates as-is.
+var profileBitrateFactors = map[string]float64{
+	"High":       1.25,
+	"High 10":    3,
+	"High 4:2:2": 4,
+	"High 4:4:4": 4,
 }
 
+// DefaultRelayFlashVersionPrefixes are the default flash version prefixes used
+// to detect relayed publishers.
+var DefaultRelayFlashVersionPrefixes = Strings{"FMLE"}
+
 // Exporter collects metrics from a nginx rtmp module's stats endpoint.
 type Exporter struct {
 	cfg      Config
 	logger   log.Logger
-	mutators []rtmpstats.Mutator
+	mutators []rtmpstats.NamedMutator
+	started  time.Time
+
+	// Exporters for each of multiple StatsURLs, which retrieve the stats for
+	// Collect. Only the metrics derived from their stats are exposed.
+	endpoints []*Exporter
+
+	// client used for StatsURL, or the error encountered creating it.
+	client    *http.Client
+	clientErr error
+
+	mut sync.Mutex
+
+	// stateMut is held while collecting metrics from stats so that
+	// overlapping scrapes update state one after another, rather than both
+	// starting from the same previous state.
+	stateMut sync.Mutex
+	state    scrapeState
+
+	// Whether the last scrape succeeded, used for readiness.
+	lastScrapeSucceeded bool
+
+	// Per-application descriptors generated from nameTemplate, keyed by the
+	// application and the descriptor's default name.
+	nameTemplate *template.Template
+	appDescs     map[*prometheus.Desc]descSpec
+	appDescCache map[string]*prometheus.Desc
+
+	// exporter stats
+	startTimeSeconds  *prometheus.Desc
+	scrapesTotal      prometheus.Counter
+	scrapeErrorsTotal prometheus.Counter
+	mutatorErrors     prometheus.Counter
+	decodeWarnings    *prometheus.CounterVec
+	mutatorInfo       *prometheus.Desc
+	activeConnections prometheus.Gauge
+	degraded          *prometheus.Desc
+
+	up                    *prometheus.Desc
+	scrapeDurationSeconds *prometheus.Desc
+	fetchDurationSeconds  *prometheus.Desc
+	parseDurationSeconds  *prometheus.Desc
+	responseBytes         *prometheus.Desc
+	tlsVersionInfo        *prometheus.Desc
+
+	health *prometheus.Desc
 
 	nginxBuildInfo *prometheus.Desc
 
@@ -41,213 +492,1803 @@ type Exporter struct {
 	serverBitrateOut *prometheus.Desc
 	serverRxTotal    *prometheus.Desc
 	serverTxTotal    *prometheus.Desc
+	bytesTotal       *prometheus.Desc
+
+	serverBytesUnaccounted *prometheus.Desc
+
+	serverBitrateInSmoothed  *prometheus.Desc
+	serverBitrateOutSmoothed *prometheus.Desc
+
+	serverEgressUtilization *prometheus.Desc
+
+	totalViewers    *prometheus.Desc
+	totalPublishers *prometheus.Desc
+
+	emptyApplications        *prometheus.Desc
+	duplicateStreamNames     *prometheus.Desc
+	cardinalityLimitExceeded *prometheus.Desc
+
+	// application stats
+	applicationClients       *prometheus.Desc
+	applicationActiveStreams *prometheus.Desc
+	applicationTotalStreams  *prometheus.Desc
+	streamsByResolution      *prometheus.Desc
 
 	// stream stats
-	streamUptimeSeconds *prometheus.Desc
-	streamBitrateIn     *prometheus.Desc
-	streamBitrateOut    *prometheus.Desc
-	streamRxTotal       *prometheus.Desc
-	streamTxTotal       *prometheus.Desc
-	streamClients       *prometheus.Desc
-	streamInfo          *prometheus.Desc
+	streamActive          *prometheus.Desc
+	streamUptimeSeconds   *prometheus.Desc
+	streamBitrateIn       *prometheus.Desc
+	streamBitrateOut      *prometheus.Desc
+	streamBitrateAVSum    *prometheus.Desc
+	streamBitrateOverhead *prometheus.Desc
+	streamAVBitrateRatio  *prometheus.Desc
+	streamRxTotal         *prometheus.Desc
+	streamTxTotal         *prometheus.Desc
+	streamClients         *prometheus.Desc
+	streamInfo            *prometheus.Desc
+	streamHasVideo        *prometheus.Desc
+	streamHasAudio        *prometheus.Desc
+	streamTrackInfo       *prometheus.Desc
+
+	streamBitrateInSmoothed  *prometheus.Desc
+	streamBitrateOutSmoothed *prometheus.Desc
+	streamBitrateInStddev    *prometheus.Desc
+
+	streamObservedAgeSeconds *prometheus.Desc
+
+	streamClientUptimeSeconds *prometheus.Desc
+	streamPublisherTimestamp  *prometheus.Desc
+	streamRelayActive         *prometheus.Desc
+	streamClientsOutOfSync    *prometheus.Desc
+	streamActiveClients       *prometheus.Desc
+	streamSubscriberCount     *prometheus.Desc
+	streamPublisherCount      *prometheus.Desc
+	streamInactiveClients     *prometheus.Desc
+	streamClientCountMismatch *prometheus.Desc
+	streamDroppedFramesRatio  *prometheus.Desc
+	streamStuckClients        *prometheus.Desc
+	streamClientsNoAddress    *prometheus.Desc
+	streamClientUptimeAvg     *prometheus.Desc
+	streamBitrateVsLevel      *prometheus.Desc
+	clientsBySubnet           *prometheus.Desc
+	streamClientsJoinedTotal  *prometheus.Desc
+	streamClientsLeftTotal    *prometheus.Desc
+
+	// output stats
+	streamHLSFragments *prometheus.Desc
+	streamHLSSequence  *prometheus.Desc
+	streamDVRSizeBytes *prometheus.Desc
 
 	// client stats
 	clientUptimeSeconds *prometheus.Desc
 	clientCount         *prometheus.Desc
+	clientDroppedFrames *prometheus.Desc
+	clientAVSync        *prometheus.Desc
 }
 
+// CustomMutatorType is the type of mutators passed to New, exposed by
+// rtmp_mutator_info.
+const CustomMutatorType = "custom"
+
 // New creates a new Exporter.
 func New(cfg Config, logger log.Logger, mutators ...rtmpstats.Mutator) *Exporter {
-	return &Exporter{
+	named := make([]rtmpstats.NamedMutator, 0, len(mutators))
+	for _, mut := range mutators {
+		named = append(named, rtmpstats.NamedMutator{Type: CustomMutatorType, Mutator: mut})
+	}
+	return NewWithNamedMutators(cfg, logger, named)
+}
+
+// NewWithNamedMutators creates a new Exporter whose mutators are described by
+// their type in rtmp_mutator_info.
+func NewWithNamedMutators(cfg Config, logger log.Logger, mutators []rtmpstats.NamedMutator) *Exporter {
+	if cfg.ClientUptimeBuckets == nil {
+		cfg.ClientUptimeBuckets = DefaultClientUptimeBuckets
+	}
+	if cfg.LevelMaxBitrates == nil {
+		cfg.LevelMaxBitrates = DefaultLevelMaxBitrates
+	}
+	if len(cfg.StatsURLs) == 1 {
+		cfg.StatsURL = cfg.StatsURLs[0].URL
+	}
+
+	constLabels := prometheus.Labels(cfg.ConstantLabels)
+
+	streamLabels := []string{"application", "stream"}
+	if !cfg.DropPublisherLabel {
+		streamLabels = append(streamLabels, "publisher")
+	}
+	var serverCounterLabels []string
+	if cfg.PIDLabel {
+		serverCounterLabels = []string{"pid"}
+	}
+
+	streamInfoLabels := append(append([]string{}, streamLabels...), "video_resolution", "frame_rate", "video_codec", "audio_codec", "audio_channels", "audio_sample_rate")
+	trackInfoLabels := append(append([]string{}, streamLabels...), "type", "index", "codec", "profile")
+
+	client, clientErr := newStatsClient(cfg)
+
+	var nameTemplate *template.Template
+	if cfg.MetricNameTemplate != "" {
+		var err error
+		nameTemplate, err = parseMetricNameTemplate(cfg.MetricNameTemplate)
+		if err != nil {
+			level.Error(logger).Log("msg", "ignoring metric name template", "err", err)
+		}
+	}
+
+	// appDesc creates a descriptor for a per-application metric. name
+	// excludes the namespace so that it can be used with nameTemplate.
+	appDescs := make(map[*prometheus.Desc]descSpec)
+	appDesc := func(name, help string, labels []string) *prometheus.Desc {
+		spec := descSpec{name: name, help: help, labels: labels, constLabels: constLabels}
+		desc := prometheus.NewDesc(prometheus.BuildFQName("rtmp", "", name), help, labels, constLabels)
+		appDescs[desc] = spec
+		return desc
+	}
+
+	e := &Exporter{
 		cfg:      cfg,
 		logger:   logger,
 		mutators: mutators,
+		started:  time.Now(),
+
+		client:    client,
+		clientErr: clientErr,
+
+		nameTemplate: nameTemplate,
+		appDescs:     appDescs,
+		appDescCache: make(map[string]*prometheus.Desc),
+
+		startTimeSeconds: prometheus.NewDesc(
+			prometheus.BuildFQName("rtmp", "exporter", "start_time_seconds"),
+			"Start time of the exporter since unix epoch in seconds",
+			nil, constLabels,
+		),
+		scrapesTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "rtmp",
+			Name:      "scrapes_total",
+			Help:      "Total number of times stats were scraped from the server",
+
+			ConstLabels: constLabels,
+		}),
+		scrapeErrorsTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "rtmp",
+			Name:      "scrape_errors_total",
+			Help:      "Total number of times scraping stats from the server failed",
+
+			ConstLabels: constLabels,
+		}),
+		mutatorErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "rtmp",
+			Name:      "mutator_errors_total",
+			Help:      "Total number of times a mutator failed and was skipped",
+
+			ConstLabels: constLabels,
+		}),
+		decodeWarnings: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "rtmp",
+			Subsystem: "stats",
+			Name:      "decode_warnings_total",
+			Help:      "Total number of elements of the given type skipped because they couldn't be decoded",
+
+			ConstLabels: constLabels,
+		}, []string{"element"}),
+		activeConnections: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "rtmp",
+			Subsystem: "exporter",
+			Name:      "active_connections",
+			Help:      "Current number of open connections to the exporter's HTTP server, including those being drained during shutdown",
+
+			ConstLabels: constLabels,
+		}),
+		mutatorInfo: prometheus.NewDesc(
+			prometheus.BuildFQName("rtmp", "mutator", "info"),
+			"Info about each configured mutator, in the order they're applied",
+			[]string{"index", "type"}, constLabels,
+		),
+		degraded: prometheus.NewDesc(
+			prometheus.BuildFQName("rtmp", "", "degraded"),
+			"Whether one or more mutators were skipped during the last scrape",
+			nil, constLabels,
+		),
+
+		up: prometheus.NewDesc(
+			prometheus.BuildFQName("rtmp", "", "up"),
+			"Whether the last scrape of the stats succeeded",
+			nil, constLabels,
+		),
+		scrapeDurationSeconds: prometheus.NewDesc(
+			prometheus.BuildFQName("rtmp", "", "scrape_duration_seconds"),
+			"Time spent fetching and parsing the stats during the last scrape",
+			nil, constLabels,
+		),
+		fetchDurationSeconds: prometheus.NewDesc(
+			prometheus.BuildFQName("rtmp", "", "fetch_duration_seconds"),
+			"Time spent reading the stats document from its source during the last scrape",
+			nil, constLabels,
+		),
+		parseDurationSeconds: prometheus.NewDesc(
+			prometheus.BuildFQName("rtmp", "", "parse_duration_seconds"),
+			"Time spent decoding and mutating the stats document during the last scrape",
+			nil, constLabels,
+		),
+		responseBytes: prometheus.NewDesc(
+			prometheus.BuildFQName("rtmp", "stats", "response_bytes"),
+			"Size of the stats document read during the last scrape",
+			nil, constLabels,
+		),
+		tlsVersionInfo: prometheus.NewDesc(
+			prometheus.BuildFQName("rtmp", "stats", "tls_version_info"),
+			"TLS version and cipher suite negotiated when fetching the stats during the last scrape",
+			[]string{"version", "cipher"},
+			constLabels,
+		),
+
+		health: prometheus.NewDesc(
+			prometheus.BuildFQName("rtmp", "", "health"),
+			"Whether the last scrape succeeded with an expected stream present and no skipped mutators",
+			nil, constLabels,
+		),
 
 		nginxBuildInfo: prometheus.NewDesc(
 			prometheus.BuildFQName("rtmp", "", "nginx_build_info"),
 			"Info about the running nginx server",
 			[]string{"nginx_version", "nginx_rtmp_version", "compiler", "built"},
-			nil,
+			constLabels,
 		),
 
 		serverBitrateIn: prometheus.NewDesc(
 			prometheus.BuildFQName("rtmp", "server", "bitrate_in"),
 			"Current incoming bitrate to the server",
-			nil, nil,
+			nil, constLabels,
 		),
 		serverBitrateOut: prometheus.NewDesc(
 			prometheus.BuildFQName("rtmp", "server", "bitrate_out"),
 			"Current outgoing bitrate from the server",
-			nil, nil,
+			nil, constLabels,
+		),
+		serverEgressUtilization: prometheus.NewDesc(
+			prometheus.BuildFQName("rtmp", "server", "egress_utilization_ratio"),
+			"Ratio of the current outgoing bitrate from the server to the configured outgoing bandwidth cap",
+			nil, constLabels,
+		),
+		serverBitrateInSmoothed: prometheus.NewDesc(
+			prometheus.BuildFQName("rtmp", "server", "bitrate_in_smoothed"),
+			"Exponential moving average of the incoming bitrate to the server across scrapes",
+			nil, constLabels,
+		),
+		serverBitrateOutSmoothed: prometheus.NewDesc(
+			prometheus.BuildFQName("rtmp", "server", "bitrate_out_smoothed"),
+			"Exponential moving average of the outgoing bitrate from the server across scrapes",
+			nil, constLabels,
 		),
 		serverRxTotal: prometheus.NewDesc(
 			prometheus.BuildFQName("rtmp", "server", "bytes_read_total"),
 			"Total amount of bytes read by the server",
-			nil, nil,
+			serverCounterLabels, constLabels,
 		),
 		serverTxTotal: prometheus.NewDesc(
 			prometheus.BuildFQName("rtmp", "server", "bytes_sent_total"),
 			"Total amount of bytes sent by the server",
-			nil, nil,
+			serverCounterLabels, constLabels,
+		),
+
+		bytesTotal: prometheus.NewDesc(
+			prometheus.BuildFQName("rtmp", "", "bytes_total"),
+			"Total amount of bytes transferred by the server in the given direction",
+			append([]string{"direction"}, serverCounterLabels...),
+			constLabels,
 		),
 
-		streamUptimeSeconds: prometheus.NewDesc(
-			prometheus.BuildFQName("rtmp", "stream", "uptime_seconds"),
+		totalViewers: prometheus.NewDesc(
+			prometheus.BuildFQName("rtmp", "", "total_viewers"),
+			"Current number of non-publishing clients across all streams",
+			nil, constLabels,
+		),
+		totalPublishers: prometheus.NewDesc(
+			prometheus.BuildFQName("rtmp", "", "total_publishers"),
+			"Current number of publishing clients across all streams",
+			nil, constLabels,
+		),
+
+		serverBytesUnaccounted: prometheus.NewDesc(
+			prometheus.BuildFQName("rtmp", "server", "bytes_unaccounted"),
+			"Bytes read by the server not accounted for by the bytes read of any listed stream",
+			nil, constLabels,
+		),
+		emptyApplications: prometheus.NewDesc(
+			prometheus.BuildFQName("rtmp", "", "applications_empty"),
+			"Current number of applications without any streams",
+			nil, constLabels,
+		),
+		duplicateStreamNames: prometheus.NewDesc(
+			prometheus.BuildFQName("rtmp", "", "duplicate_stream_names"),
+			"Current number of stream names used by more than one application",
+			nil, constLabels,
+		),
+		cardinalityLimitExceeded: prometheus.NewDesc(
+			prometheus.BuildFQName("rtmp", "", "cardinality_limit_exceeded"),
+			"Whether metrics were truncated because the given limit was exceeded",
+			[]string{"limit"}, constLabels,
+		),
+
+		applicationClients: appDesc(
+			prometheus.BuildFQName("", "application", "clients"),
+			"Current number of clients connected to all streams of the given application",
+			[]string{"application"},
+		),
+		applicationActiveStreams: appDesc(
+			prometheus.BuildFQName("", "application", "active_streams"),
+			"Current number of active streams in the given application",
+			[]string{"application"},
+		),
+		applicationTotalStreams: appDesc(
+			prometheus.BuildFQName("", "application", "total_streams"),
+			"Current number of streams in the given application, including inactive streams",
+			[]string{"application"},
+		),
+		streamsByResolution: appDesc(
+			prometheus.BuildFQName("", "", "streams_by_resolution"),
+			"Current number of streams in the given application with the given video resolution",
+			[]string{"application", "resolution"},
+		),
+
+		streamActive: appDesc(
+			prometheus.BuildFQName("", "stream", "active"),
+			"Whether the given stream is active",
+			streamLabels,
+		),
+		streamUptimeSeconds: appDesc(
+			prometheus.BuildFQName("", "stream", "uptime_seconds"),
 			"Uptime of the stream in seconds",
-			[]string{"application", "stream", "publisher"},
-			nil,
+			streamLabels,
 		),
-		streamBitrateIn: prometheus.NewDesc(
-			prometheus.BuildFQName("rtmp", "stream", "bitrate_in"),
+		streamObservedAgeSeconds: appDesc(
+			prometheus.BuildFQName("", "stream", "observed_age_seconds"),
+			"Time in seconds since the exporter first saw the stream",
+			streamLabels,
+		),
+		streamBitrateIn: appDesc(
+			prometheus.BuildFQName("", "stream", "bitrate_in"),
 			"Current incoming bitrate for the given stream",
-			[]string{"application", "stream", "publisher"},
-			nil,
+			streamLabels,
 		),
-		streamBitrateOut: prometheus.NewDesc(
-			prometheus.BuildFQName("rtmp", "stream", "bitrate_out"),
+		streamBitrateOut: appDesc(
+			prometheus.BuildFQName("", "stream", "bitrate_out"),
 			"Current outgoing bitrate for the given stream",
-			[]string{"application", "stream", "publisher"},
-			nil,
+			streamLabels,
+		),
+		streamBitrateInSmoothed: appDesc(
+			prometheus.BuildFQName("", "stream", "bitrate_in_smoothed"),
+			"Exponential moving average of the incoming bitrate for the given stream across scrapes",
+			streamLabels,
 		),
-		streamRxTotal: prometheus.NewDesc(
-			prometheus.BuildFQName("rtmp", "stream", "bytes_read_total"),
+		streamBitrateOutSmoothed: appDesc(
+			prometheus.BuildFQName("", "stream", "bitrate_out_smoothed"),
+			"Exponential moving average of the outgoing bitrate for the given stream across scrapes",
+			streamLabels,
+		),
+		streamBitrateInStddev: appDesc(
+			prometheus.BuildFQName("", "stream", "bitrate_in_stddev"),
+			"Standard deviation of the incoming bitrate for the given stream over recent scrapes",
+			streamLabels,
+		),
+		streamBitrateAVSum: appDesc(
+			prometheus.BuildFQName("", "stream", "bitrate_av_sum"),
+			"Sum of the current video and audio bitrates for the given stream",
+			streamLabels,
+		),
+		streamBitrateOverhead: appDesc(
+			prometheus.BuildFQName("", "stream", "bitrate_overhead"),
+			"Current incoming bitrate for the given stream not accounted for by video and audio",
+			streamLabels,
+		),
+		streamAVBitrateRatio: appDesc(
+			prometheus.BuildFQName("", "stream", "av_bitrate_ratio"),
+			"Ratio of the current audio bitrate to the current video bitrate for the given stream. Zero when there is no video bitrate",
+			streamLabels,
+		),
+		streamRxTotal: appDesc(
+			prometheus.BuildFQName("", "stream", "bytes_read_total"),
 			"Total amount of bytes read for the given stream",
-			[]string{"application", "stream", "publisher"},
-			nil,
+			streamLabels,
 		),
-		streamTxTotal: prometheus.NewDesc(
-			prometheus.BuildFQName("rtmp", "stream", "bytes_sent_total"),
+		streamTxTotal: appDesc(
+			prometheus.BuildFQName("", "stream", "bytes_sent_total"),
 			"Total amount of bytes sent by the given stream",
-			[]string{"application", "stream", "publisher"},
-			nil,
+			streamLabels,
 		),
-		streamClients: prometheus.NewDesc(
-			prometheus.BuildFQName("rtmp", "stream", "current_clients"),
+		streamClients: appDesc(
+			prometheus.BuildFQName("", "stream", "current_clients"),
 			"Current number of clients connected to the given stream",
-			[]string{"application", "stream", "publisher"},
-			nil,
+			streamLabels,
 		),
-		streamInfo: prometheus.NewDesc(
-			prometheus.BuildFQName("rtmp", "stream", "info"),
+		streamInfo: appDesc(
+			prometheus.BuildFQName("", "stream", "info"),
 			"Info for a specific stream",
-			[]string{"application", "stream", "publisher", "video_resolution", "frame_rate", "video_codec", "audio_codec", "audio_channels", "audio_sample_rate"},
-			nil,
+			streamInfoLabels,
+		),
+		streamHasVideo: appDesc(
+			prometheus.BuildFQName("", "stream", "has_video"),
+			"Whether the given stream has a video track",
+			streamLabels,
+		),
+		streamHasAudio: appDesc(
+			prometheus.BuildFQName("", "stream", "has_audio"),
+			"Whether the given stream has an audio track",
+			streamLabels,
+		),
+		streamTrackInfo: appDesc(
+			prometheus.BuildFQName("", "stream", "track_info"),
+			"Info for each video and audio track of a specific stream",
+			trackInfoLabels,
+		),
+		streamClientUptimeSeconds: appDesc(
+			prometheus.BuildFQName("", "stream", "client_uptime_seconds"),
+			"Distribution of the uptime of clients viewing the given stream",
+			streamLabels,
+		),
+		streamPublisherTimestamp: appDesc(
+			prometheus.BuildFQName("", "stream", "publisher_timestamp_milliseconds"),
+			"Current timestamp of the publisher for the given stream",
+			streamLabels,
+		),
+		streamRelayActive: appDesc(
+			prometheus.BuildFQName("", "stream", "relay_active"),
+			"Whether the publisher of the given stream is a relay from another server",
+			streamLabels,
+		),
+		streamClientsOutOfSync: appDesc(
+			prometheus.BuildFQName("", "stream", "clients_out_of_sync"),
+			"Current number of clients for the given stream whose A-V sync drift exceeds the configured threshold",
+			[]string{"application", "stream"},
+		),
+		streamSubscriberCount: appDesc(
+			prometheus.BuildFQName("", "stream", "subscribers"),
+			"Current number of non-publishing clients for the given stream, as reported by the server when available",
+			[]string{"application", "stream"},
+		),
+		streamPublisherCount: appDesc(
+			prometheus.BuildFQName("", "stream", "publishers"),
+			"Current number of publishing clients for the given stream, as reported by the server when available",
+			[]string{"application", "stream"},
+		),
+		streamActiveClients: appDesc(
+			prometheus.BuildFQName("", "stream", "active_clients"),
+			"Current number of clients for the given stream that nginx reports as active",
+			[]string{"application", "stream"},
+		),
+		streamInactiveClients: appDesc(
+			prometheus.BuildFQName("", "stream", "inactive_clients"),
+			"Current number of clients for the given stream that nginx reports as inactive",
+			[]string{"application", "stream"},
+		),
+		streamDroppedFramesRatio: appDesc(
+			prometheus.BuildFQName("", "stream", "dropped_frames_ratio"),
+			"Approximate ratio of frames dropped by clients of the given stream, based on client uptime and the stream frame rate",
+			streamLabels,
+		),
+		streamStuckClients: appDesc(
+			prometheus.BuildFQName("", "stream", "stuck_clients"),
+			"Current number of viewers for the given active stream whose timestamp hasn't advanced since the previous scrape",
+			streamLabels,
+		),
+		streamClientsNoAddress: appDesc(
+			prometheus.BuildFQName("", "stream", "clients_missing_address"),
+			"Current number of clients for the given stream without an address",
+			[]string{"application", "stream"},
+		),
+		streamClientUptimeAvg: appDesc(
+			prometheus.BuildFQName("", "stream", "client_uptime_avg_seconds"),
+			"Average uptime in seconds of the viewers of the given stream. Zero when there are no viewers",
+			[]string{"application", "stream"},
+		),
+		streamBitrateVsLevel: appDesc(
+			prometheus.BuildFQName("", "stream", "bitrate_vs_level_ratio"),
+			"Ratio of the video bitrate of the given stream to the maximum bitrate of its declared H.264 level. Values above 1 indicate an encoder exceeding its level",
+			[]string{"application", "stream"},
+		),
+		clientsBySubnet: appDesc(
+			prometheus.BuildFQName("", "clients", "by_subnet"),
+			"Current number of viewers for the given stream whose address is in the given subnet",
+			[]string{"application", "stream", "subnet"},
+		),
+		streamClientsJoinedTotal: appDesc(
+			prometheus.BuildFQName("", "stream", "clients_joined_total"),
+			"Total number of viewers that joined the given stream between scrapes",
+			[]string{"application", "stream"},
+		),
+		streamClientsLeftTotal: appDesc(
+			prometheus.BuildFQName("", "stream", "clients_left_total"),
+			"Total number of viewers that left the given stream between scrapes",
+			[]string{"application", "stream"},
+		),
+		streamClientCountMismatch: appDesc(
+			prometheus.BuildFQName("", "stream", "client_count_mismatch"),
+			"Difference between the reported number of clients for the given stream and the number of clients listed",
+			[]string{"application", "stream"},
+		),
+
+		streamHLSFragments: appDesc(
+			prometheus.BuildFQName("", "stream", "hls_fragments"),
+			"Current number of HLS fragments for the given stream",
+			streamLabels,
+		),
+		streamHLSSequence: appDesc(
+			prometheus.BuildFQName("", "stream", "hls_sequence"),
+			"Current HLS media sequence number for the given stream",
+			streamLabels,
+		),
+		streamDVRSizeBytes: appDesc(
+			prometheus.BuildFQName("", "stream", "dvr_size_bytes"),
+			"Current size of the recording for the given stream",
+			streamLabels,
 		),
 
-		clientUptimeSeconds: prometheus.NewDesc(
-			prometheus.BuildFQName("rtmp", "client", "uptime_seconds"),
+		clientUptimeSeconds: appDesc(
+			prometheus.BuildFQName("", "client", "uptime_seconds"),
 			"Total amount of time a client viewed with a stream",
 			[]string{"application", "stream", "client"},
-			nil,
 		),
-		clientCount: prometheus.NewDesc(
-			prometheus.BuildFQName("rtmp", "client", "count"),
+		clientCount: appDesc(
+			prometheus.BuildFQName("", "client", "count"),
 			"Client count for a specific stream",
 			[]string{"application", "stream", "client"},
-			nil,
 		),
+		clientDroppedFrames: appDesc(
+			prometheus.BuildFQName("", "client", "dropped_frames"),
+			"Number of frames dropped for a client of a specific stream",
+			[]string{"application", "stream", "client_id"},
+		),
+		clientAVSync: appDesc(
+			prometheus.BuildFQName("", "client", "avsync_ms"),
+			"Current A-V sync drift in milliseconds of a client of a specific stream",
+			[]string{"application", "stream", "client_id"},
+		),
+	}
+
+	if len(cfg.StatsURLs) > 1 {
+		for _, ep := range cfg.StatsURLs {
+			epCfg := cfg
+			epCfg.StatsURL = ep.URL
+			epCfg.StatsURLs = nil
+			epCfg.ConstantLabels = Labels{}
+			for name, value := range cfg.ConstantLabels {
+				epCfg.ConstantLabels[name] = value
+			}
+			epCfg.ConstantLabels["instance"] = ep.Name
+			e.endpoints = append(e.endpoints, NewWithNamedMutators(epCfg, log.With(logger, "instance", ep.Name), mutators))
+		}
 	}
+	return e
 }
 
 // Describe describes all the metrics that will be exposed by the rtmp
 // exporter. It implements prometheus.Collector.
 func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- e.startTimeSeconds
+	ch <- e.scrapesTotal.Desc()
+	ch <- e.scrapeErrorsTotal.Desc()
+	ch <- e.mutatorInfo
+	ch <- e.activeConnections.Desc()
+
+	for _, src := range e.sources() {
+		src.describeSource(ch)
+	}
+}
+
+// describeSource describes the metrics derived from retrieving the stats of a
+// single source.
+func (e *Exporter) describeSource(ch chan<- *prometheus.Desc) {
+	ch <- e.mutatorErrors.Desc()
+	e.decodeWarnings.Describe(ch)
+	ch <- e.degraded
+	ch <- e.up
+	ch <- e.scrapeDurationSeconds
+	ch <- e.fetchDurationSeconds
+	ch <- e.parseDurationSeconds
+	ch <- e.responseBytes
+	ch <- e.tlsVersionInfo
+	ch <- e.health
 	ch <- e.nginxBuildInfo
 }
 
 // Collect fetches the statistics from the configured server, and delivers them
 // as Prometheus metrics. It implements prometheus.Collector.
 func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
-	s, err := e.getStats()
-	if err != nil {
-		level.Error(e.logger).Log("msg", "failed to get stats", "err", err)
-		return
-	}
-
-	ch <- prometheus.MustNewConstMetric(e.nginxBuildInfo, prometheus.GaugeValue, 1, s.NGINXVersion, s.NGINXRTMPVersion, s.Compiler, s.Built.String())
+	e.collect(context.Background(), ch)
+}
 
-	ch <- prometheus.MustNewConstMetric(e.serverBitrateIn, prometheus.GaugeValue, float64(s.BitrateIn))
-	ch <- prometheus.MustNewConstMetric(e.serverBitrateOut, prometheus.GaugeValue, float64(s.BitrateOut))
-	ch <- prometheus.MustNewConstMetric(e.serverRxTotal, prometheus.CounterValue, float64(s.BytesIn))
-	ch <- prometheus.MustNewConstMetric(e.serverTxTotal, prometheus.CounterValue, float64(s.BytesOut))
+// collect implements Collect, fetching the statistics with ctx. Exporter
+// metrics are delivered once, without the instance label of multiple
+// StatsURLs.
+func (e *Exporter) collect(ctx context.Context, ch chan<- prometheus.Metric) {
+	e.scrapesTotal.Inc()
+	succeeded, failed := e.collectSources(ctx, ch)
+	e.scrapeErrorsTotal.Add(float64(failed))
+	e.setLastScrapeSucceeded(succeeded)
+	e.collectExporterMetrics(ch)
+}
 
-	for _, app := range s.Applications {
-		for _, stream := range app.Streams {
-			var publisher rtmpstats.Client
-			for _, cli := range stream.Clients {
-				if cli.Publishing {
-					publisher = cli
-					break
-				}
-			}
+// collectExporterMetrics delivers the metrics about the exporter itself.
+func (e *Exporter) collectExporterMetrics(ch chan<- prometheus.Metric) {
+	e.sendConstMetric(ch, e.startTimeSeconds, prometheus.GaugeValue, float64(e.started.UnixNano())/1e9)
+	ch <- e.scrapesTotal
+	ch <- e.scrapeErrorsTotal
+	for i, mut := range e.mutators {
+		e.sendConstMetric(ch, e.mutatorInfo, prometheus.GaugeValue, 1, strconv.Itoa(i), mut.Type)
+	}
+	ch <- e.activeConnections
+}
 
-			ch <- prometheus.MustNewConstMetric(e.streamUptimeSeconds, prometheus.CounterValue, float64(stream.Uptime.Seconds()), app.Name, stream.Name, publisher.ID)
-			ch <- prometheus.MustNewConstMetric(e.streamBitrateIn, prometheus.GaugeValue, float64(stream.BitrateIn), app.Name, stream.Name, publisher.ID)
-			ch <- prometheus.MustNewConstMetric(e.streamBitrateOut, prometheus.GaugeValue, float64(stream.BitrateOut), app.Name, stream.Name, publisher.ID)
-			ch <- prometheus.MustNewConstMetric(e.streamRxTotal, prometheus.CounterValue, float64(stream.BytesIn), app.Name, stream.Name, publisher.ID)
-			ch <- prometheus.MustNewConstMetric(e.streamTxTotal, prometheus.CounterValue, float64(stream.BytesOut), app.Name, stream.Name, publisher.ID)
-			ch <- prometheus.MustNewConstMetric(e.streamClients, prometheus.GaugeValue, float64(stream.NumClients), app.Name, stream.Name, publisher.ID)
+// sources returns the Exporters that retrieve stats: one for each of multiple
+// StatsURLs, or e itself.
+func (e *Exporter) sources() []*Exporter {
+	if len(e.endpoints) > 0 {
+		return e.endpoints
+	}
+	return []*Exporter{e}
+}
 
-			ch <- prometheus.MustNewConstMetric(e.streamInfo, prometheus.GaugeValue, 1,
-				app.Name, stream.Name, publisher.ID,
-				fmt.Sprintf("%dx%d", stream.VideoWidth, stream.VideoHeight), fmt.Sprintf("%d", stream.VideoFramerate), stream.VideoCodec,
-				stream.AudioCodec, fmt.Sprintf("%d", stream.AudioChannels), fmt.Sprintf("%d", stream.AudioSampleRate),
-			)
+// collectSources collects the metrics of every source concurrently so that a
+// slow or failing endpoint doesn't hold up the others. succeeded is true when
+// any source succeeded, and failed is the number of sources that failed.
+func (e *Exporter) collectSources(ctx context.Context, ch chan<- prometheus.Metric) (succeeded bool, failed int) {
+	srcs := e.sources()
+	results := make([]bool, len(srcs))
 
-			for _, cli := range stream.Clients {
-				if cli.Publishing {
-					continue
-				}
+	var wg sync.WaitGroup
+	for i, src := range srcs {
+		wg.Add(1)
+		go func(i int, src *Exporter) {
+			defer wg.Done()
+			results[i] = src.collectSource(ctx, ch)
+		}(i, src)
+	}
+	wg.Wait()
 
-				ch <- prometheus.MustNewConstMetric(e.clientUptimeSeconds, prometheus.CounterValue, cli.Uptime.Seconds(), app.Name, stream.Name, cli.ID)
-				ch <- prometheus.MustNewConstMetric(e.clientCount, prometheus.GaugeValue, float64(cli.EntriesCount), app.Name, stream.Name, cli.ID)
-			}
+	for _, ok := range results {
+		if ok {
+			succeeded = true
+		} else {
+			failed++
 		}
 	}
+	return succeeded, failed
 }
 
-func (e *Exporter) getStats() (*rtmpstats.Stats, error) {
-	switch {
-	case e.cfg.StatsFile != "":
-		return e.getStatsFromFile()
-	default:
-		return e.getStatsFromURL()
-	}
-}
+// collectSource retrieves the stats of a single source and delivers the
+// metrics derived from them, returning whether retrieving the stats
+// succeeded.
+func (e *Exporter) collectSource(ctx context.Context, ch chan<- prometheus.Metric) bool {
+	var (
+		info           scrapeInfo
+		succeeded      bool
+		healthy        bool
+		scrapeDuration time.Duration
+	)
+
+	defer func() {
+		ch <- e.mutatorErrors
+		e.decodeWarnings.Collect(ch)
+		e.sendConstMetric(ch, e.degraded, prometheus.GaugeValue, boolToFloat(info.mutatorErrors > 0))
+		e.sendConstMetric(ch, e.up, prometheus.GaugeValue, boolToFloat(succeeded))
+		e.sendConstMetric(ch, e.scrapeDurationSeconds, prometheus.GaugeValue, scrapeDuration.Seconds())
+		e.sendConstMetric(ch, e.fetchDurationSeconds, prometheus.GaugeValue, info.fetchDuration.Seconds())
+		e.sendConstMetric(ch, e.parseDurationSeconds, prometheus.GaugeValue, info.parseDuration.Seconds())
+		e.sendConstMetric(ch, e.responseBytes, prometheus.GaugeValue, float64(info.responseBytes))
+		if info.tls != nil {
+			e.sendConstMetric(ch, e.tlsVersionInfo, prometheus.GaugeValue, 1, tlsVersionName(info.tls.Version), tls.CipherSuiteName(info.tls.CipherSuite))
+		}
+		e.sendConstMetric(ch, e.health, prometheus.GaugeValue, boolToFloat(healthy))
+	}()
 
-func (e *Exporter) getStatsFromFile() (*rtmpstats.Stats, error) {
-	f, err := os.Open(e.cfg.StatsFile)
+	scrapeStart := time.Now()
+	s, err := e.fetchAndParse(ctx, &info)
+	scrapeDuration = time.Since(scrapeStart)
+	e.mutatorErrors.Add(float64(info.mutatorErrors))
 	if err != nil {
-		return nil, fmt.Errorf("opening file: %w", err)
+		level.Error(e.logger).Log("msg", "failed to get stats", "err", err)
+		return false
 	}
-	defer f.Close()
 
-	s, err := rtmpstats.Unmarshal(f, e.mutators...)
-	if err != nil {
-		return nil, fmt.Errorf("reading stats: %w", err)
+	for element, n := range s.DecodeWarnings {
+		e.decodeWarnings.WithLabelValues(element).Add(float64(n))
+	}
+
+	succeeded = true
+	healthy = e.hasExpectedStream(s) && (info.mutatorErrors == 0 || e.cfg.HealthIgnoreMutatorErrors)
+
+	if !e.cfg.UseDataTimestamp || info.modTime.IsZero() {
+		e.collectStats(ch, s)
+		return true
+	}
+
+	timestamped := make(chan prometheus.Metric)
+	go func() {
+		defer close(timestamped)
+		e.collectStats(timestamped, s)
+	}()
+	for m := range timestamped {
+		ch <- prometheus.NewMetricWithTimestamp(info.modTime, m)
 	}
-	return s, nil
+	return true
 }
 
-func (e *Exporter) getStatsFromURL() (*rtmpstats.Stats, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), e.cfg.Timeout)
-	defer cancel()
+// collectStats delivers the metrics derived from s, updating the state
+// retained between scrapes.
+func (e *Exporter) collectStats(ch chan<- prometheus.Metric, s *rtmpstats.Stats) {
+	e.stateMut.Lock()
+	defer e.stateMut.Unlock()
 
-	req, err := http.NewRequestWithContext(ctx, "GET", e.cfg.StatsURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("building request: %w", err)
+	prev := e.state
+	cur := e.collectStatsFrom(ch, s, prev)
+	if e.cfg.ChangeLog {
+		e.logStreamChanges(prev.streamSnapshots, cur.streamSnapshots)
+	}
+	e.state = cur
+}
+
+// collectStatsFrom delivers the metrics derived from s given the state of the
+// previous scrape, returning the state of this scrape. A zero prev collects
+// the metrics as if on a first scrape.
+func (e *Exporter) collectStatsFrom(ch chan<- prometheus.Metric, s *rtmpstats.Stats, prev scrapeState) scrapeState {
+	cur := newScrapeState()
+
+	e.sendConstMetric(ch, e.nginxBuildInfo, prometheus.GaugeValue, 1, s.NGINXVersion, s.NGINXRTMPVersion, s.Compiler, s.Built.String())
+
+	smooth := func(key string, bitrate int64) float64 {
+		value := float64(bitrate)
+		if last, ok := prev.smoothedBitrates[key]; ok {
+			value = e.cfg.BitrateSmoothing*value + (1-e.cfg.BitrateSmoothing)*last
+		}
+		cur.smoothedBitrates[key] = value
+		return value
 	}
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("executing request: %w", err)
+	e.sendConstMetric(ch, e.serverBitrateIn, prometheus.GaugeValue, float64(s.BitrateIn))
+	e.sendConstMetric(ch, e.serverBitrateOut, prometheus.GaugeValue, float64(s.BitrateOut))
+	if e.cfg.BandwidthCapBitsOut > 0 {
+		e.sendConstMetric(ch, e.serverEgressUtilization, prometheus.GaugeValue, float64(s.BitrateOut)/float64(e.cfg.BandwidthCapBitsOut))
+	}
+	if e.cfg.BitrateSmoothing > 0 {
+		e.sendConstMetric(ch, e.serverBitrateInSmoothed, prometheus.GaugeValue, smooth("server/in", s.BitrateIn))
+		e.sendConstMetric(ch, e.serverBitrateOutSmoothed, prometheus.GaugeValue, smooth("server/out", s.BitrateOut))
 	}
-	defer resp.Body.Close()
 
-	s, err := rtmpstats.Unmarshal(resp.Body, e.mutators...)
-	if err != nil {
-		return nil, fmt.Errorf("reading stats: %w", err)
+	var serverCounterLabels []string
+	if e.cfg.PIDLabel {
+		serverCounterLabels = []string{strconv.Itoa(s.PID)}
 	}
+	if e.cfg.UnifiedByteMetrics {
+		e.sendConstMetric(ch, e.bytesTotal, prometheus.CounterValue, float64(s.BytesIn), append([]string{"in"}, serverCounterLabels...)...)
+		e.sendConstMetric(ch, e.bytesTotal, prometheus.CounterValue, float64(s.BytesOut), append([]string{"out"}, serverCounterLabels...)...)
+	} else {
+		e.sendConstMetric(ch, e.serverRxTotal, prometheus.CounterValue, float64(s.BytesIn), serverCounterLabels...)
+		e.sendConstMetric(ch, e.serverTxTotal, prometheus.CounterValue, float64(s.BytesOut), serverCounterLabels...)
+	}
+	e.sendConstMetric(ch, e.serverBytesUnaccounted, prometheus.GaugeValue, float64(unaccountedBytes(s)))
+
+	var (
+		totalViewers, totalPublishers    int
+		emptyApplications                int
+		exposedStreams, exposedClients   int
+		streamsExceeded, clientsExceeded bool
+		streamApplications               = make(map[string][]string)
+		now                              = time.Now()
+	)
 
-	return s, nil
+	for _, app := range s.Applications {
+		if !e.includeApplication(app) {
+			continue
+		}
+
+		desc := func(d *prometheus.Desc) *prometheus.Desc {
+			return e.applicationDesc(app.Name, d)
+		}
+
+		if len(app.Streams) == 0 {
+			emptyApplications++
+		}
+
+		var (
+			appClients, activeStreams int
+			resolutions               = make(map[string]int)
+		)
+		for _, stream := range app.Streams {
+			streamApplications[stream.Name] = append(streamApplications[stream.Name], app.Name)
+			appClients += stream.NumClients
+			totalViewers += stream.SubscriberCount()
+			totalPublishers += stream.PublisherCount()
+			if stream.Active {
+				activeStreams++
+			}
+			resolutions[streamResolution(stream)]++
+		}
+		e.sendConstMetric(ch, desc(e.applicationClients), prometheus.GaugeValue, float64(appClients), app.Name)
+		e.sendConstMetric(ch, desc(e.applicationActiveStreams), prometheus.GaugeValue, float64(activeStreams), app.Name)
+		e.sendConstMetric(ch, desc(e.applicationTotalStreams), prometheus.GaugeValue, float64(len(app.Streams)), app.Name)
+		for resolution, count := range resolutions {
+			e.sendConstMetric(ch, desc(e.streamsByResolution), prometheus.GaugeValue, float64(count), app.Name, resolution)
+		}
+
+		for _, stream := range app.Streams {
+			if e.cfg.MaxStreams > 0 && exposedStreams >= e.cfg.MaxStreams {
+				streamsExceeded = true
+				continue
+			}
+			exposedStreams++
+
+			var stuck int
+			if e.cfg.DetectStuckClients {
+				for _, cli := range stream.Clients {
+					if cli.Publishing {
+						continue
+					}
+
+					key := app.Name + "/" + stream.Name + "/" + cli.ID
+					if last, ok := prev.clientTimestamps[key]; ok && stream.Active && cli.Timestamp == last {
+						stuck++
+					}
+					cur.clientTimestamps[key] = cli.Timestamp
+				}
+			}
+
+			var smoothedIn, smoothedOut float64
+			if e.cfg.BitrateSmoothing > 0 {
+				key := "stream/" + app.Name + "/" + stream.Name
+				smoothedIn, smoothedOut = smooth(key+"/in", stream.BitrateIn), smooth(key+"/out", stream.BitrateOut)
+			}
+
+			var window *bitrateWindow
+			if e.cfg.BitrateWindow > 0 {
+				key := app.Name + "/" + stream.Name
+				window = prev.bitrateWindows[key].add(e.cfg.BitrateWindow, float64(stream.BitrateIn))
+				cur.bitrateWindows[key] = window
+			}
+
+			var observedAge float64
+			if e.cfg.TrackObservedAge {
+				key := app.Name + "/" + stream.Name
+				firstSeen, ok := prev.streamFirstSeen[key]
+				if !ok {
+					firstSeen = now
+				}
+				cur.streamFirstSeen[key] = firstSeen
+				observedAge = now.Sub(firstSeen).Seconds()
+			}
+
+			if e.cfg.ChangeLog {
+				cur.streamSnapshots[app.Name+"/"+stream.Name] = streamSnapshot{
+					application: app.Name,
+					stream:      stream.Name,
+					bitrateIn:   stream.BitrateIn,
+					resolution:  streamResolution(stream),
+				}
+			}
+
+			var churn *clientChurn
+			if e.cfg.TrackClientChurn {
+				key := app.Name + "/" + stream.Name
+				churn = prev.streamChurn[key].update(stream.Clients)
+				cur.streamChurn[key] = churn
+			}
+
+			if e.cfg.DropZeroSeries && isIdleStream(stream) {
+				e.sendConstMetric(ch, desc(e.streamActive), prometheus.GaugeValue, 0, e.streamLabelValues(app.Name, stream.Name, "")...)
+				continue
+			}
+
+			// Per-stream metrics are repeated for each publisher, including
+			// stream-wide values like bitrates and byte counts.
+			for _, publisher := range e.streamPublishers(stream) {
+				streamLabels := e.streamLabelValues(app.Name, stream.Name, publisher.ID)
+
+				e.sendConstMetric(ch, desc(e.streamActive), prometheus.GaugeValue, boolToFloat(stream.Active), streamLabels...)
+				e.sendConstMetric(ch, desc(e.streamUptimeSeconds), prometheus.CounterValue, e.uptimeSeconds(stream.Uptime), streamLabels...)
+				if e.cfg.TrackObservedAge {
+					e.sendConstMetric(ch, desc(e.streamObservedAgeSeconds), prometheus.GaugeValue, observedAge, streamLabels...)
+				}
+				e.sendConstMetric(ch, desc(e.streamBitrateIn), prometheus.GaugeValue, float64(stream.BitrateIn), streamLabels...)
+				e.sendConstMetric(ch, desc(e.streamBitrateOut), prometheus.GaugeValue, float64(stream.BitrateOut), streamLabels...)
+				if e.cfg.BitrateSmoothing > 0 {
+					e.sendConstMetric(ch, desc(e.streamBitrateInSmoothed), prometheus.GaugeValue, smoothedIn, streamLabels...)
+					e.sendConstMetric(ch, desc(e.streamBitrateOutSmoothed), prometheus.GaugeValue, smoothedOut, streamLabels...)
+				}
+				if window != nil {
+					e.sendConstMetric(ch, desc(e.streamBitrateInStddev), prometheus.GaugeValue, window.stddev(), streamLabels...)
+				}
+
+				avSum := stream.BitrateVideo + stream.BitrateAudio
+				overhead := stream.BitrateIn - avSum
+				if overhead < 0 {
+					overhead = 0
+				}
+				e.sendConstMetric(ch, desc(e.streamBitrateAVSum), prometheus.GaugeValue, float64(avSum), streamLabels...)
+				e.sendConstMetric(ch, desc(e.streamBitrateOverhead), prometheus.GaugeValue, float64(overhead), streamLabels...)
+				e.sendConstMetric(ch, desc(e.streamAVBitrateRatio), prometheus.GaugeValue, avBitrateRatio(stream), streamLabels...)
+
+				e.sendConstMetric(ch, desc(e.streamRxTotal), prometheus.CounterValue, float64(stream.BytesIn), streamLabels...)
+				e.sendConstMetric(ch, desc(e.streamTxTotal), prometheus.CounterValue, float64(stream.BytesOut), streamLabels...)
+				e.sendConstMetric(ch, desc(e.streamClients), prometheus.GaugeValue, float64(stream.NumClients), streamLabels...)
+
+				infoLabels := append(append([]string{}, streamLabels...),
+					fmt.Sprintf("%dx%d", stream.VideoWidth, stream.VideoHeight), fmt.Sprintf("%d", stream.VideoFramerate), stream.VideoCodec,
+					stream.AudioCodec, fmt.Sprintf("%d", stream.AudioChannels), fmt.Sprintf("%d", stream.AudioSampleRate),
+				)
+				e.sendConstMetric(ch, desc(e.streamInfo), prometheus.GaugeValue, 1, infoLabels...)
+				e.sendConstMetric(ch, desc(e.streamHasVideo), prometheus.GaugeValue, boolToFloat(stream.VideoCodec != ""), streamLabels...)
+				e.sendConstMetric(ch, desc(e.streamHasAudio), prometheus.GaugeValue, boolToFloat(stream.AudioCodec != ""), streamLabels...)
+				for i, track := range stream.VideoTracks {
+					trackLabels := append(append([]string{}, streamLabels...), "video", strconv.Itoa(i), track.Codec, track.Profile)
+					e.sendConstMetric(ch, desc(e.streamTrackInfo), prometheus.GaugeValue, 1, trackLabels...)
+				}
+				for i, track := range stream.AudioTracks {
+					trackLabels := append(append([]string{}, streamLabels...), "audio", strconv.Itoa(i), track.Codec, track.Profile)
+					e.sendConstMetric(ch, desc(e.streamTrackInfo), prometheus.GaugeValue, 1, trackLabels...)
+				}
+
+				if m, err := e.clientUptimeHistogram(desc(e.streamClientUptimeSeconds), stream, streamLabels...); err != nil {
+					level.Warn(e.logger).Log("msg", "skipping invalid metric", "err", err)
+				} else {
+					ch <- m
+				}
+
+				if publisher.Publishing {
+					e.sendConstMetric(ch, desc(e.streamPublisherTimestamp), prometheus.GaugeValue, float64(publisher.Timestamp.Milliseconds()), streamLabels...)
+					e.sendConstMetric(ch, desc(e.streamRelayActive), prometheus.GaugeValue, boolToFloat(e.isRelay(publisher)), streamLabels...)
+				}
+
+				if stream.HLS != nil {
+					e.sendConstMetric(ch, desc(e.streamHLSFragments), prometheus.GaugeValue, float64(stream.HLS.Fragments), streamLabels...)
+					e.sendConstMetric(ch, desc(e.streamHLSSequence), prometheus.GaugeValue, float64(stream.HLS.Sequence), streamLabels...)
+				}
+				if stream.DVR != nil {
+					e.sendConstMetric(ch, desc(e.streamDVRSizeBytes), prometheus.GaugeValue, float64(stream.DVR.Size), streamLabels...)
+				}
+
+				e.sendConstMetric(ch, desc(e.streamDroppedFramesRatio), prometheus.GaugeValue, droppedFramesRatio(stream), streamLabels...)
+
+				if e.cfg.DetectStuckClients {
+					e.sendConstMetric(ch, desc(e.streamStuckClients), prometheus.GaugeValue, float64(stuck), streamLabels...)
+				}
+			}
+
+			var outOfSync, listedClients, noAddress, activeClients int
+			for _, cli := range stream.Clients {
+				listedClients += cli.EntriesCount
+				if cli.AVSync > e.cfg.AVSyncThresholdMs || -cli.AVSync > e.cfg.AVSyncThresholdMs {
+					outOfSync += cli.EntriesCount
+				}
+				if cli.Active {
+					activeClients += cli.EntriesCount
+				}
+				if cli.Address == "" {
+					noAddress++
+				}
+			}
+			e.sendConstMetric(ch, desc(e.streamClientsOutOfSync), prometheus.GaugeValue, float64(outOfSync), app.Name, stream.Name)
+			e.sendConstMetric(ch, desc(e.streamClientsNoAddress), prometheus.GaugeValue, float64(noAddress), app.Name, stream.Name)
+			e.sendConstMetric(ch, desc(e.streamSubscriberCount), prometheus.GaugeValue, float64(stream.SubscriberCount()), app.Name, stream.Name)
+			e.sendConstMetric(ch, desc(e.streamPublisherCount), prometheus.GaugeValue, float64(stream.PublisherCount()), app.Name, stream.Name)
+			e.sendConstMetric(ch, desc(e.streamActiveClients), prometheus.GaugeValue, float64(activeClients), app.Name, stream.Name)
+			e.sendConstMetric(ch, desc(e.streamInactiveClients), prometheus.GaugeValue, float64(listedClients-activeClients), app.Name, stream.Name)
+			e.sendConstMetric(ch, desc(e.streamClientUptimeAvg), prometheus.GaugeValue, e.uptimeSeconds(averageViewerUptime(stream)), app.Name, stream.Name)
+			if ratio, ok := e.bitrateVsLevel(stream); ok {
+				e.sendConstMetric(ch, desc(e.streamBitrateVsLevel), prometheus.GaugeValue, ratio, app.Name, stream.Name)
+			}
+
+			if e.cfg.ClientSubnetPrefix > 0 {
+				subnets := make(map[string]int)
+				for _, cli := range stream.Clients {
+					if !cli.Publishing {
+						subnets[e.clientSubnet(cli.Address)] += cli.EntriesCount
+					}
+				}
+				for subnet, count := range subnets {
+					e.sendConstMetric(ch, desc(e.clientsBySubnet), prometheus.GaugeValue, float64(count), app.Name, stream.Name, subnet)
+				}
+			}
+			e.sendConstMetric(ch, desc(e.streamClientCountMismatch), prometheus.GaugeValue, float64(stream.NumClients-listedClients), app.Name, stream.Name)
+
+			if churn != nil {
+				e.sendConstMetric(ch, desc(e.streamClientsJoinedTotal), prometheus.CounterValue, float64(churn.joined), app.Name, stream.Name)
+				e.sendConstMetric(ch, desc(e.streamClientsLeftTotal), prometheus.CounterValue, float64(churn.left), app.Name, stream.Name)
+			}
+
+			for _, cli := range stream.Clients {
+				if cli.Publishing {
+					continue
+				}
+
+				if e.cfg.MaxClients > 0 && exposedClients >= e.cfg.MaxClients {
+					clientsExceeded = true
+					continue
+				}
+				exposedClients++
+
+				e.sendConstMetric(ch, desc(e.clientUptimeSeconds), prometheus.CounterValue, e.uptimeSeconds(cli.Uptime), app.Name, stream.Name, cli.ID)
+				e.sendConstMetric(ch, desc(e.clientCount), prometheus.GaugeValue, float64(cli.EntriesCount), app.Name, stream.Name, cli.ID)
+			}
+
+			if e.cfg.ExposeClientMetrics {
+				for _, cli := range stream.Clients {
+					e.sendConstMetric(ch, desc(e.clientDroppedFrames), prometheus.GaugeValue, float64(cli.DroppedFrames), app.Name, stream.Name, cli.ID)
+					e.sendConstMetric(ch, desc(e.clientAVSync), prometheus.GaugeValue, float64(cli.AVSync), app.Name, stream.Name, cli.ID)
+				}
+			}
+		}
+	}
+
+	e.sendConstMetric(ch, e.totalViewers, prometheus.GaugeValue, float64(totalViewers))
+	e.sendConstMetric(ch, e.totalPublishers, prometheus.GaugeValue, float64(totalPublishers))
+	e.sendConstMetric(ch, e.emptyApplications, prometheus.GaugeValue, float64(emptyApplications))
+	e.sendConstMetric(ch, e.duplicateStreamNames, prometheus.GaugeValue, float64(e.countDuplicateStreamNames(streamApplications)))
+
+	if streamsExceeded {
+		level.Warn(e.logger).Log("msg", "stream limit exceeded, omitting metrics for some streams", "limit", e.cfg.MaxStreams)
+	}
+	if clientsExceeded {
+		level.Warn(e.logger).Log("msg", "client limit exceeded, omitting metrics for some clients", "limit", e.cfg.MaxClients)
+	}
+	e.sendConstMetric(ch, e.cardinalityLimitExceeded, prometheus.GaugeValue, boolToFloat(streamsExceeded), "streams")
+	e.sendConstMetric(ch, e.cardinalityLimitExceeded, prometheus.GaugeValue, boolToFloat(clientsExceeded), "clients")
+	return cur
+}
+
+// countDuplicateStreamNames returns the number of stream names in
+// streamApplications used by more than one application, logging each of them
+// when WarnOnDuplicateStreamNames is set.
+func (e *Exporter) countDuplicateStreamNames(streamApplications map[string][]string) int {
+	names := make([]string, 0, len(streamApplications))
+	for name, apps := range streamApplications {
+		if len(apps) > 1 {
+			names = append(names, name)
+		}
+	}
+
+	if e.cfg.WarnOnDuplicateStreamNames {
+		sort.Strings(names)
+		for _, name := range names {
+			level.Warn(e.logger).Log("msg", "stream name used by multiple applications", "stream", name, "applications", strings.Join(streamApplications[name], ","))
+		}
+	}
+	return len(names)
+}
+
+// Reset clears all state retained between scrapes.
+func (e *Exporter) Reset() {
+	for _, ep := range e.endpoints {
+		ep.Reset()
+	}
+
+	e.stateMut.Lock()
+	defer e.stateMut.Unlock()
+	e.state = scrapeState{}
+}
+
+// scrapeState is the state retained between scrapes. Every scrape builds a new
+// scrapeState from what it saw, which replaces the previous one so that
+// clients and streams that disappeared are evicted. The maps are only filled
+// in when the features using them are enabled.
+type scrapeState struct {
+	// Last seen client timestamps keyed by application/stream/client, used
+	// for detecting stuck clients.
+	clientTimestamps map[string]time.Duration
+
+	// Smoothed bitrates keyed by server or application/stream and direction.
+	smoothedBitrates map[string]float64
+
+	// Viewers of every stream keyed by application/stream.
+	streamChurn map[string]*clientChurn
+
+	// Recent incoming bitrates of every stream keyed by application/stream.
+	bitrateWindows map[string]*bitrateWindow
+
+	// Last seen values of every stream for ChangeLog keyed by
+	// application/stream. Nil until the first scrape.
+	streamSnapshots map[string]streamSnapshot
+
+	// Time every stream was first seen keyed by application/stream.
+	streamFirstSeen map[string]time.Time
+}
+
+func newScrapeState() scrapeState {
+	return scrapeState{
+		clientTimestamps: make(map[string]time.Duration),
+		smoothedBitrates: make(map[string]float64),
+		streamChurn:      make(map[string]*clientChurn),
+		bitrateWindows:   make(map[string]*bitrateWindow),
+		streamSnapshots:  make(map[string]streamSnapshot),
+		streamFirstSeen:  make(map[string]time.Time),
+	}
+}
+
+// clientChurn tracks the viewers of a stream across scrapes.
+type clientChurn struct {
+	viewers      map[string]struct{}
+	joined, left int
+}
+
+// update returns the churn after comparing the viewers in clients against
+// the previous viewers. The viewers of a stream seen for the first time are
+// not counted as having joined.
+func (c *clientChurn) update(clients []rtmpstats.Client) *clientChurn {
+	res := &clientChurn{viewers: make(map[string]struct{}, len(clients))}
+	for _, cli := range clients {
+		if !cli.Publishing {
+			res.viewers[cli.ID] = struct{}{}
+		}
+	}
+	if c == nil {
+		return res
+	}
+
+	res.joined, res.left = c.joined, c.left
+	for id := range res.viewers {
+		if _, ok := c.viewers[id]; !ok {
+			res.joined++
+		}
+	}
+	for id := range c.viewers {
+		if _, ok := res.viewers[id]; !ok {
+			res.left++
+		}
+	}
+	return res
+}
+
+// streamSnapshot holds the values of a stream that ChangeLog logs changes of.
+type streamSnapshot struct {
+	application, stream string
+	bitrateIn           int64
+	resolution          string
+}
+
+// logStreamChanges logs the differences between the streams of the previous
+// and current scrape. Nothing is logged when there's no previous scrape.
+func (e *Exporter) logStreamChanges(prev, cur map[string]streamSnapshot) {
+	if prev == nil {
+		return
+	}
+	logger := level.Info(e.logger)
+
+	for key, s := range cur {
+		p, ok := prev[key]
+		if !ok {
+			logger.Log("msg", "stream appeared", "application", s.application, "stream", s.stream, "bitrate_in", s.bitrateIn, "resolution", s.resolution)
+			continue
+		}
+
+		if change := math.Abs(float64(s.bitrateIn - p.bitrateIn)); change > 0 && change > e.cfg.ChangeLogBitrateThreshold*float64(p.bitrateIn) {
+			logger.Log("msg", "stream bitrate changed", "application", s.application, "stream", s.stream, "old", p.bitrateIn, "new", s.bitrateIn)
+		}
+		if s.resolution != p.resolution {
+			logger.Log("msg", "stream resolution changed", "application", s.application, "stream", s.stream, "old", p.resolution, "new", s.resolution)
+		}
+	}
+	for key, p := range prev {
+		if _, ok := cur[key]; !ok {
+			logger.Log("msg", "stream disappeared", "application", p.application, "stream", p.stream)
+		}
+	}
+}
+
+// bitrateWindow is a ring buffer of the most recent bitrates of a stream.
+type bitrateWindow struct {
+	samples []float64
+	next    int
+}
+
+// add returns a copy of the window with v added, overwriting the oldest
+// sample once the window holds size samples.
+func (w *bitrateWindow) add(size int, v float64) *bitrateWindow {
+	res := &bitrateWindow{samples: make([]float64, 0, size)}
+	if w != nil {
+		res.samples = append(res.samples, w.samples...)
+		res.next = w.next
+	}
+
+	if len(res.samples) < size {
+		res.samples = append(res.samples, v)
+		return res
+	}
+	res.samples[res.next] = v
+	res.next = (res.next + 1) % size
+	return res
+}
+
+// stddev returns the population standard deviation of the samples.
+func (w *bitrateWindow) stddev() float64 {
+	if len(w.samples) == 0 {
+		return 0
+	}
+
+	var mean float64
+	for _, v := range w.samples {
+		mean += v
+	}
+	mean /= float64(len(w.samples))
+
+	var variance float64
+	for _, v := range w.samples {
+		variance += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(variance / float64(len(w.samples)))
+}
+
+// RenderText writes the metrics for s to w in the Prometheus text exposition
+// format, using an Exporter created from the default flag values. Only
+// metrics derived from s are written; exporter metrics like scrape counts are
+// omitted.
+func RenderText(s *rtmpstats.Stats, w io.Writer) error {
+	var cfg Config
+	cfg.RegisterFlagsWithPrefix("", flag.NewFlagSet("", flag.ContinueOnError))
+	return New(cfg, log.NewNopLogger()).RenderText(s, w)
+}
+
+// RenderText writes the metrics for s to w in the Prometheus text exposition
+// format, using the same descriptors as Collect. State retained between
+// scrapes is neither used nor updated, so rendering doesn't affect what later
+// scrapes report.
+func (e *Exporter) RenderText(s *rtmpstats.Stats, w io.Writer) error {
+	reg := prometheus.NewRegistry()
+	if err := reg.Register(statsCollector{e: e, s: s}); err != nil {
+		return err
+	}
+
+	mfs, err := reg.Gather()
+	if err != nil {
+		return err
+	}
+
+	enc := expfmt.NewEncoder(w, expfmt.FmtText)
+	for _, mf := range mfs {
+		if err := enc.Encode(mf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statsCollector is a prometheus.Collector that delivers the metrics for a
+// fixed Stats.
+type statsCollector struct {
+	e *Exporter
+	s *rtmpstats.Stats
+}
+
+// Describe implements prometheus.Collector. No descriptors are sent, making
+// statsCollector an unchecked collector.
+func (c statsCollector) Describe(ch chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector.
+func (c statsCollector) Collect(ch chan<- prometheus.Metric) {
+	c.e.collectStatsFrom(ch, c.s, scrapeState{})
+}
+
+// sendConstMetric sends a constant metric to ch. Metrics that can't be
+// created, such as from label values that aren't valid UTF-8, are logged and
+// skipped rather than failing the entire scrape.
+func (e *Exporter) sendConstMetric(ch chan<- prometheus.Metric, desc *prometheus.Desc, valueType prometheus.ValueType, value float64, labelValues ...string) {
+	m, err := prometheus.NewConstMetric(desc, valueType, value, labelValues...)
+	if err != nil {
+		level.Warn(e.logger).Log("msg", "skipping invalid metric", "desc", desc, "err", err)
+		return
+	}
+	ch <- m
+}
+
+// hasExpectedStream returns true if s has an exposed stream that matches
+// HealthExpectedStreams, or any exposed stream when no patterns are set.
+func (e *Exporter) hasExpectedStream(s *rtmpstats.Stats) bool {
+	for _, app := range s.Applications {
+		if !e.includeApplication(app) {
+			continue
+		}
+		for _, stream := range app.Streams {
+			if e.cfg.HealthExpectedStreams.Empty() || e.cfg.HealthExpectedStreams.Match(app.Name+"/"+stream.Name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// includeApplication returns true if metrics should be exposed for app.
+func (e *Exporter) includeApplication(app rtmpstats.Application) bool {
+	if !e.cfg.ApplicationAllowlist.Empty() && !e.cfg.ApplicationAllowlist.Match(app.Name) {
+		return false
+	}
+
+	if e.cfg.IngestOnly {
+		for _, stream := range app.Streams {
+			if stream.Publishing {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// isRelay returns true if the publishing client is a relay from another server,
+// detected by its flash version starting with one of the configured prefixes.
+func (e *Exporter) isRelay(publisher rtmpstats.Client) bool {
+	for _, prefix := range e.cfg.RelayFlashVersionPrefixes {
+		if strings.HasPrefix(publisher.FlashVersion, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// streamResolution returns the video resolution of a stream as WIDTHxHEIGHT,
+// or unknown if the stream has no video meta information.
+func streamResolution(stream rtmpstats.Stream) string {
+	if stream.VideoWidth == 0 && stream.VideoHeight == 0 {
+		return "unknown"
+	}
+	return fmt.Sprintf("%dx%d", stream.VideoWidth, stream.VideoHeight)
+}
+
+// isIdleStream returns true if stream is inactive and has zero bitrates, byte
+// counts, and clients.
+func isIdleStream(stream rtmpstats.Stream) bool {
+	return !stream.Active &&
+		stream.BitrateIn == 0 && stream.BitrateOut == 0 &&
+		stream.BitrateVideo == 0 && stream.BitrateAudio == 0 &&
+		stream.BytesIn == 0 && stream.BytesOut == 0 &&
+		stream.NumClients == 0 && len(stream.Clients) == 0 &&
+		stream.SubscriberCount() == 0 && stream.PublisherCount() == 0
+}
+
+// droppedFramesRatio approximates the ratio of frames dropped across all
+// clients of a stream. nginx doesn't report how many frames were sent, so the
+// total is estimated as the sum of each client's uptime multiplied by the
+// stream's frame rate. Returns 0 when the estimate is zero.
+func droppedFramesRatio(stream rtmpstats.Stream) float64 {
+	var dropped int
+	var uptime time.Duration
+	for _, cli := range stream.Clients {
+		dropped += cli.DroppedFrames
+		uptime += cli.Uptime
+	}
+
+	totalFrames := uptime.Seconds() * float64(stream.VideoFramerate)
+	if totalFrames <= 0 {
+		return 0
+	}
+	return float64(dropped) / totalFrames
+}
+
+// clientSubnet returns the subnet in CIDR notation that addr belongs to,
+// using the configured prefix lengths. "unknown" is returned if addr isn't an
+// IP address.
+func (e *Exporter) clientSubnet(addr string) string {
+	ip := net.ParseIP(rtmpstats.NormalizeAddress(addr))
+	if ip == nil {
+		return "unknown"
+	}
+
+	mask := net.CIDRMask(e.cfg.ClientSubnetPrefix, 32)
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	} else {
+		mask = net.CIDRMask(e.cfg.ClientSubnetPrefixV6, 128)
+	}
+	subnet := net.IPNet{IP: ip.Mask(mask), Mask: mask}
+	return subnet.String()
+}
+
+// unaccountedBytes returns the bytes read by the server minus the bytes read
+// by all streams, clamped to zero.
+func unaccountedBytes(s *rtmpstats.Stats) int64 {
+	unaccounted := s.BytesIn
+	for _, app := range s.Applications {
+		for _, stream := range app.Streams {
+			unaccounted -= stream.BytesIn
+		}
+	}
+
+	if unaccounted < 0 {
+		return 0
+	}
+	return unaccounted
+}
+
+// averageViewerUptime returns the mean uptime of the non-publishing clients
+// of stream, or 0 if there are none.
+func averageViewerUptime(stream rtmpstats.Stream) time.Duration {
+	var (
+		total   time.Duration
+		viewers int
+	)
+	for _, cli := range stream.Clients {
+		if cli.Publishing {
+			continue
+		}
+		total += cli.Uptime
+		viewers++
+	}
+
+	if viewers == 0 {
+		return 0
+	}
+	return total / time.Duration(viewers)
+}
+
+// bitrateVsLevel returns the ratio of the video bitrate of stream to the
+// maximum bitrate of its declared H.264 level and profile. ok is false if the
+// stream isn't H.264 or its level is unknown.
+//
+// Only the bitrate limit of the level is checked. The resolution and frame
+// rate of the stream are not used, so streams exceeding the frame size or
+// macroblock rate of their level aren't detected.
+func (e *Exporter) bitrateVsLevel(stream rtmpstats.Stream) (ratio float64, ok bool) {
+	if stream.VideoCodec != "H264" {
+		return 0, false
+	}
+	kbps, ok := e.cfg.LevelMaxBitrates[stream.VideoLevel]
+	if !ok {
+		return 0, false
+	}
+	max := float64(kbps * 1000)
+	if factor, ok := profileBitrateFactors[stream.VideoProfile]; ok {
+		max *= factor
+	}
+	return float64(stream.BitrateVideo) / max, true
+}
+
+// avBitrateRatio returns the ratio of the audio bitrate to the video bitrate
+// of stream, or 0 if stream has no video bitrate.
+func avBitrateRatio(stream rtmpstats.Stream) float64 {
+	if stream.BitrateVideo <= 0 {
+		return 0
+	}
+	return float64(stream.BitrateAudio) / float64(stream.BitrateVideo)
+}
+
+// uptimeSeconds returns d in seconds, rounded down to the configured
+// UptimeResolution.
+func (e *Exporter) uptimeSeconds(d time.Duration) float64 {
+	if e.cfg.UptimeResolution > 0 {
+		d = d.Truncate(e.cfg.UptimeResolution)
+	}
+	return d.Seconds()
+}
+
+// streamPublishers returns the publishing clients to expose per-stream
+// metrics for. Only the first publisher is returned when the publisher label
+// is disabled, as series for multiple publishers would otherwise collide. A
+// zero Client is returned for streams without a publisher.
+func (e *Exporter) streamPublishers(stream rtmpstats.Stream) []rtmpstats.Client {
+	var publishers []rtmpstats.Client
+	for _, cli := range stream.Clients {
+		if !cli.Publishing {
+			continue
+		}
+		publishers = append(publishers, cli)
+		if e.cfg.DropPublisherLabel {
+			break
+		}
+	}
+
+	if len(publishers) == 0 {
+		return []rtmpstats.Client{{}}
+	}
+	return publishers
+}
+
+// streamLabelValues returns the label values for per-stream metrics.
+func (e *Exporter) streamLabelValues(app, stream, publisher string) []string {
+	if e.cfg.DropPublisherLabel {
+		return []string{app, stream}
+	}
+	return []string{app, stream, publisher}
+}
+
+// clientUptimeHistogram builds a histogram observing the uptime of every
+// non-publishing client in the stream.
+func (e *Exporter) clientUptimeHistogram(desc *prometheus.Desc, stream rtmpstats.Stream, labelValues ...string) (prometheus.Metric, error) {
+	var (
+		count   uint64
+		sum     float64
+		buckets = make(map[float64]uint64, len(e.cfg.ClientUptimeBuckets))
+	)
+	for _, b := range e.cfg.ClientUptimeBuckets {
+		buckets[b] = 0
+	}
+
+	for _, cli := range stream.Clients {
+		if cli.Publishing {
+			continue
+		}
+
+		uptime := e.uptimeSeconds(cli.Uptime)
+		count++
+		sum += uptime
+
+		for _, b := range e.cfg.ClientUptimeBuckets {
+			if uptime <= b {
+				buckets[b]++
+			}
+		}
+	}
+
+	return prometheus.NewConstHistogram(desc, count, sum, buckets, labelValues...)
+}
+
+// Stats retrieves stats from the configured source and applies the exporter's
+// mutators. It is used by Collect and may be used directly to obtain stats
+// without going through a Prometheus collection. The stats of multiple
+// StatsURLs are retrieved concurrently and merged with rtmpstats.Merge.
+// Endpoints that fail are logged and left out of the result; an error is only
+// returned when all of them fail.
+func (e *Exporter) Stats(ctx context.Context) (*rtmpstats.Stats, error) {
+	if len(e.endpoints) == 0 {
+		var info scrapeInfo
+		return e.fetchAndParse(ctx, &info)
+	}
+
+	stats := make([]*rtmpstats.Stats, len(e.endpoints))
+	errs := make([]error, len(e.endpoints))
+
+	var wg sync.WaitGroup
+	for i, ep := range e.endpoints {
+		wg.Add(1)
+		go func(i int, ep *Exporter) {
+			defer wg.Done()
+			stats[i], errs[i] = ep.Stats(ctx)
+		}(i, ep)
+	}
+	wg.Wait()
+
+	var (
+		docs    []rtmpstats.Stats
+		lastErr error
+	)
+	for i, err := range errs {
+		if err != nil {
+			level.Warn(e.endpoints[i].logger).Log("msg", "failed to get stats, omitting endpoint", "err", err)
+			lastErr = fmt.Errorf("%s: %w", e.cfg.StatsURLs[i].Name, err)
+			continue
+		}
+		docs = append(docs, *stats[i])
+	}
+	if len(docs) == 0 {
+		return nil, lastErr
+	}
+	return rtmpstats.Merge(docs...), nil
+}
+
+// scrapeInfo holds information about retrieving the stats for a scrape.
+type scrapeInfo struct {
+	fetchDuration time.Duration
+	parseDuration time.Duration
+	responseBytes int
+	mutatorErrors int
+
+	// tls is the connection state used to fetch the stats over HTTPS.
+	tls *tls.ConnectionState
+
+	// modTime is the latest modification time of the stats files.
+	modTime time.Time
+}
+
+// fetchAndParse fetches and parses the stats, recording information about
+// the process into info. When reading from files and FileRetryDelay is set, a
+// document that appears to be partially written is retried once after the
+// delay.
+func (e *Exporter) fetchAndParse(ctx context.Context, info *scrapeInfo) (*rtmpstats.Stats, error) {
+	attempts := 1
+	if len(e.cfg.StatsFiles) > 0 && e.cfg.FileRetryDelay > 0 {
+		attempts = 2
+	}
+
+	for attempt := 1; ; attempt++ {
+		fetchStart := time.Now()
+		buf, err := e.fetch(ctx, info)
+		info.fetchDuration += time.Since(fetchStart)
+		if err != nil {
+			return nil, err
+		}
+		info.responseBytes = len(buf)
+
+		parseStart := time.Now()
+		s, mutatorErrors, err := e.parse(buf)
+		info.parseDuration += time.Since(parseStart)
+		info.mutatorErrors = mutatorErrors
+		if err == nil || attempt >= attempts || !isPartialDocument(err) {
+			return s, err
+		}
+
+		level.Warn(e.logger).Log("msg", "stats file may be partially written, retrying", "err", err, "delay", e.cfg.FileRetryDelay)
+		select {
+		case <-time.After(e.cfg.FileRetryDelay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// isPartialDocument returns true if err indicates that the stats document
+// ended unexpectedly.
+func isPartialDocument(err error) bool {
+	var syntaxErr *xml.SyntaxError
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &syntaxErr)
+}
+
+// fetch reads the raw stats document from the configured source.
+func (e *Exporter) fetch(ctx context.Context, info *scrapeInfo) ([]byte, error) {
+	switch {
+	case len(e.cfg.StatsFiles) > 0:
+		return e.fetchFromFile(ctx, info)
+	case e.cfg.StatsCommand != "":
+		return e.fetchFromCommand(ctx)
+	default:
+		return e.fetchFromURL(ctx, info)
+	}
+}
+
+// fetchFromFile reads all configured stats files, giving up once ctx is done
+// or the file timeout expires. A read that hangs, such as on an unresponsive
+// network file system, can't be interrupted and is abandoned in the
+// background.
+func (e *Exporter) fetchFromFile(ctx context.Context, info *scrapeInfo) ([]byte, error) {
+	timeout := e.cfg.FileTimeout
+	if timeout == 0 {
+		timeout = e.cfg.Timeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	type result struct {
+		buf     []byte
+		modTime time.Time
+		err     error
+	}
+	done := make(chan result, 1)
+	go func() {
+		buf, modTime, err := e.readFiles()
+		done <- result{buf: buf, modTime: modTime, err: err}
+	}()
+
+	select {
+	case res := <-done:
+		info.modTime = res.modTime
+		return res.buf, res.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("reading file: %w", ctx.Err())
+	}
+}
+
+// readFiles reads all configured stats files, returning their contents along
+// with their latest modification time. When there is more than one file,
+// their contents are concatenated to be parsed as multiple documents.
+func (e *Exporter) readFiles() ([]byte, time.Time, error) {
+	var (
+		res     []byte
+		modTime time.Time
+	)
+	for _, path := range e.cfg.StatsFiles {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, time.Time{}, fmt.Errorf("reading file: %w", err)
+		}
+		fi, err := f.Stat()
+		if err == nil {
+			var buf []byte
+			buf, err = ioutil.ReadAll(f)
+			res = append(res, buf...)
+		}
+		f.Close()
+		if err != nil {
+			return nil, time.Time{}, fmt.Errorf("reading file: %w", err)
+		}
+
+		res = append(res, '\n')
+		if fi.ModTime().After(modTime) {
+			modTime = fi.ModTime()
+		}
+	}
+	return res, modTime, nil
+}
+
+// fetchFromCommand runs the configured command and returns its stdout.
+func (e *Exporter) fetchFromCommand(ctx context.Context) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, e.cfg.Timeout)
+	defer cancel()
+
+	args := strings.Fields(e.cfg.StatsCommand)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("stats command is empty")
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("running command: %w: %s", err, msg)
+		}
+		return nil, fmt.Errorf("running command: %w", err)
+	}
+	return stdout.Bytes(), nil
+}
+
+func (e *Exporter) fetchFromURL(ctx context.Context, info *scrapeInfo) ([]byte, error) {
+	if e.clientErr != nil {
+		return nil, fmt.Errorf("creating client: %w", e.clientErr)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", e.cfg.StatsURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("building request: %w", err)
+	}
+	req.Header.Set("Accept", "application/xml, text/xml")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("executing request: %w", err)
+	}
+	defer resp.Body.Close()
+	info.tls = resp.TLS
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	buf, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+	return buf, nil
+}
+
+// parse decodes a raw stats document and applies the exporter's mutators.
+// When IgnoreMutatorErrors is set, failing mutators are skipped and the
+// number of skipped mutators is returned.
+func (e *Exporter) parse(buf []byte) (*rtmpstats.Stats, int, error) {
+	var (
+		s   *rtmpstats.Stats
+		err error
+	)
+	if e.cfg.MultiDocument || len(e.cfg.StatsFiles) > 1 {
+		s, err = rtmpstats.UnmarshalAll(bytes.NewReader(buf))
+	} else {
+		s, err = rtmpstats.Unmarshal(bytes.NewReader(buf))
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("reading stats: %w", err)
+	}
+
+	var mutatorErrors int
+	for i, mut := range e.mutators {
+		if err := mut.Mutator(s); err != nil {
+			if !e.cfg.IgnoreMutatorErrors {
+				return nil, 0, fmt.Errorf("reading stats: %w", err)
+			}
+			level.Warn(e.logger).Log("msg", "skipping failed mutator", "mutator", i, "type", mut.Type, "err", err)
+			mutatorErrors++
+		}
+	}
+	return s, mutatorErrors, nil
 }
@@ -0,0 +1,1778 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/rfratto/rtmp_exporter/rtmpstats"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExporter_Stats(t *testing.T) {
+	t.Run("file", func(t *testing.T) {
+		e := New(Config{StatsFiles: StringSlice{"testdata/stats.xml"}}, log.NewNopLogger(), rtmpstats.WithoutStreamMeta())
+		s, err := e.Stats(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, "1.19.0", s.NGINXVersion)
+		require.Equal(t, "", s.Applications[0].Streams[0].VideoCodec)
+	})
+
+	t.Run("url", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.ServeFile(w, r, "testdata/stats.xml")
+		}))
+		defer srv.Close()
+
+		e := New(Config{StatsURL: srv.URL, Timeout: time.Second}, log.NewNopLogger())
+		s, err := e.Stats(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, "streamName", s.Applications[0].Streams[0].Name)
+	})
+
+	t.Run("accept header", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Accept") != "application/xml, text/xml" {
+				w.Header().Set("Content-Type", "text/html")
+				_, _ = w.Write([]byte("<html><body>not xml</body></html>"))
+				return
+			}
+			http.ServeFile(w, r, "testdata/stats.xml")
+		}))
+		defer srv.Close()
+
+		e := New(Config{StatsURL: srv.URL, Timeout: time.Second}, log.NewNopLogger())
+		_, err := e.Stats(context.Background())
+		require.NoError(t, err)
+	})
+
+	t.Run("bad status", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "oops", http.StatusInternalServerError)
+		}))
+		defer srv.Close()
+
+		e := New(Config{StatsURL: srv.URL, Timeout: time.Second}, log.NewNopLogger())
+		_, err := e.Stats(context.Background())
+		require.EqualError(t, err, "unexpected status code 500")
+	})
+
+	t.Run("command", func(t *testing.T) {
+		e := New(Config{StatsCommand: "cat testdata/stats.xml", Timeout: time.Second}, log.NewNopLogger())
+		s, err := e.Stats(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, "streamName", s.Applications[0].Streams[0].Name)
+	})
+
+	t.Run("command stderr", func(t *testing.T) {
+		e := New(Config{StatsCommand: "cat testdata/missing.xml", Timeout: time.Second}, log.NewNopLogger())
+		_, err := e.Stats(context.Background())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "testdata/missing.xml")
+	})
+
+	t.Run("command timeout", func(t *testing.T) {
+		e := New(Config{StatsCommand: "sleep 5", Timeout: 50 * time.Millisecond}, log.NewNopLogger())
+
+		start := time.Now()
+		_, err := e.Stats(context.Background())
+		require.Error(t, err)
+		require.Less(t, int64(time.Since(start)), int64(5*time.Second))
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.ServeFile(w, r, "testdata/stats.xml")
+		}))
+		defer srv.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		e := New(Config{StatsURL: srv.URL, Timeout: time.Second}, log.NewNopLogger())
+		_, err := e.Stats(ctx)
+		require.True(t, errors.Is(err, context.Canceled))
+	})
+}
+
+func TestRenderText(t *testing.T) {
+	e := New(Config{StatsFiles: StringSlice{"testdata/stats.xml"}}, log.NewNopLogger())
+	s, err := e.Stats(context.Background())
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, RenderText(s, &buf))
+
+	out := buf.String()
+	require.Contains(t, out, "# TYPE rtmp_stream_uptime_seconds counter\n")
+	require.Contains(t, out, `rtmp_stream_uptime_seconds{application="live",publisher="1",stream="streamName"} 500.003`)
+	require.Contains(t, out, `rtmp_server_bytes_read_total 1.30057972e+08`)
+	require.False(t, strings.Contains(out, "rtmp_scrapes_total"), "exporter metrics should be omitted")
+}
+
+func TestExporter_MultipleFiles(t *testing.T) {
+	cfg := Config{StatsFiles: StringSlice{"testdata/stats.xml", "testdata/stats_apps.xml"}}
+	s, err := New(cfg, log.NewNopLogger()).Stats(context.Background())
+	require.NoError(t, err)
+
+	require.Equal(t, int64(130057972+900000), s.BytesIn)
+
+	var apps []string
+	for _, app := range s.Applications {
+		apps = append(apps, app.Name)
+	}
+	require.Equal(t, []string{"live", "tenant-a", "tenant-b", "playback"}, apps)
+
+	var streams []string
+	for _, stream := range s.Applications[0].Streams {
+		streams = append(streams, stream.Name)
+	}
+	require.Equal(t, []string{"streamName", "main"}, streams)
+}
+
+func TestExporter_FileRetryDelay(t *testing.T) {
+	full, err := ioutil.ReadFile("testdata/stats.xml")
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "rtmp_exporter")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "stats.xml")
+
+	// Simulate reading the file while it's being written, with the writer
+	// finishing by the time the exporter retries.
+	var retries int
+	logger := log.LoggerFunc(func(kvs ...interface{}) error {
+		for i := 0; i+1 < len(kvs); i += 2 {
+			if kvs[i] == "msg" && kvs[i+1] == "stats file may be partially written, retrying" {
+				retries++
+				require.NoError(t, ioutil.WriteFile(path, full, 0644))
+			}
+		}
+		return nil
+	})
+
+	tt := []struct {
+		delay     time.Duration
+		expectErr bool
+	}{
+		{delay: 0, expectErr: true},
+		{delay: 10 * time.Millisecond, expectErr: false},
+	}
+
+	for _, tc := range tt {
+		require.NoError(t, ioutil.WriteFile(path, full[:len(full)/2], 0644))
+		retries = 0
+
+		e := New(Config{StatsFiles: StringSlice{path}, FileRetryDelay: tc.delay}, logger)
+		_, err := e.Stats(context.Background())
+		if tc.expectErr {
+			require.Error(t, err)
+			require.Equal(t, 0, retries)
+		} else {
+			require.NoError(t, err)
+			require.Equal(t, 1, retries)
+		}
+	}
+}
+
+func TestExporter_ClientUptimeHistogram(t *testing.T) {
+	cfg := Config{
+		StatsFiles:          StringSlice{"testdata/stats.xml"},
+		ClientUptimeBuckets: Buckets{60, 400, 600},
+	}
+
+	mfs := gather(t, New(cfg, log.NewNopLogger()))
+	mf := findFamily(t, mfs, "rtmp_stream_client_uptime_seconds")
+	require.Len(t, mf.Metric, 1)
+
+	h := mf.Metric[0].GetHistogram()
+	require.Equal(t, uint64(3), h.GetSampleCount())
+	require.InDelta(t, 36.310+371.856+496.931, h.GetSampleSum(), 0.0001)
+
+	counts := make(map[float64]uint64)
+	for _, b := range h.Bucket {
+		counts[b.GetUpperBound()] = b.GetCumulativeCount()
+	}
+	require.Equal(t, map[float64]uint64{60: 1, 400: 2, 600: 3}, counts)
+}
+
+func TestExporter_PublisherTimestamp(t *testing.T) {
+	t.Run("publisher", func(t *testing.T) {
+		mfs := gather(t, New(Config{StatsFiles: StringSlice{"testdata/stats.xml"}}, log.NewNopLogger()))
+		mf := findFamily(t, mfs, "rtmp_stream_publisher_timestamp_milliseconds")
+		require.Len(t, mf.Metric, 1)
+		require.Equal(t, float64(499599), mf.Metric[0].GetGauge().GetValue())
+	})
+
+	t.Run("no publisher", func(t *testing.T) {
+		mfs := gather(t, New(Config{StatsFiles: StringSlice{"testdata/stats_no_publisher.xml"}}, log.NewNopLogger()))
+		for _, mf := range mfs {
+			require.NotEqual(t, "rtmp_stream_publisher_timestamp_milliseconds", mf.GetName())
+		}
+	})
+}
+
+func TestExporter_Totals(t *testing.T) {
+	mfs := gather(t, New(Config{StatsFiles: StringSlice{"testdata/stats.xml"}}, log.NewNopLogger()))
+
+	viewers := findFamily(t, mfs, "rtmp_total_viewers")
+	require.Equal(t, float64(3), viewers.Metric[0].GetGauge().GetValue())
+
+	publishers := findFamily(t, mfs, "rtmp_total_publishers")
+	require.Equal(t, float64(1), publishers.Metric[0].GetGauge().GetValue())
+}
+
+func TestExporter_ApplicationAllowlist(t *testing.T) {
+	var allowlist Patterns
+	require.NoError(t, allowlist.Set("live"))
+	require.NoError(t, allowlist.Set("tenant-.*"))
+
+	cfg := Config{
+		StatsFiles:           StringSlice{"testdata/stats_apps.xml"},
+		ApplicationAllowlist: allowlist,
+	}
+	mfs := gather(t, New(cfg, log.NewNopLogger()))
+
+	apps := make(map[string]struct{})
+	for _, m := range findFamily(t, mfs, "rtmp_stream_bitrate_in").Metric {
+		apps[labelValue(m, "application")] = struct{}{}
+	}
+	require.Equal(t, map[string]struct{}{"live": {}, "tenant-a": {}, "tenant-b": {}}, apps)
+}
+
+func TestExporter_WithoutStreamMeta(t *testing.T) {
+	cfg := Config{StatsFiles: StringSlice{"testdata/stats.xml"}}
+	mfs := gather(t, New(cfg, log.NewNopLogger(), rtmpstats.WithoutStreamMeta()))
+
+	info := findFamily(t, mfs, "rtmp_stream_info")
+	require.Len(t, info.Metric, 1)
+
+	expect := map[string]string{
+		"application":       "live",
+		"stream":            "streamName",
+		"publisher":         "1",
+		"video_resolution":  "0x0",
+		"frame_rate":        "0",
+		"video_codec":       "",
+		"audio_codec":       "",
+		"audio_channels":    "0",
+		"audio_sample_rate": "0",
+	}
+	for name, value := range expect {
+		require.Equal(t, value, labelValue(info.Metric[0], name), name)
+	}
+}
+
+func TestExporter_HasVideoAudio(t *testing.T) {
+	tt := []struct {
+		file               string
+		hasVideo, hasAudio float64
+	}{
+		{file: "testdata/stats.xml", hasVideo: 1, hasAudio: 1},
+		{file: "testdata/stats_video_only.xml", hasVideo: 1, hasAudio: 0},
+	}
+
+	for _, tc := range tt {
+		mfs := gather(t, New(Config{StatsFiles: StringSlice{tc.file}}, log.NewNopLogger()))
+
+		hasVideo := findFamily(t, mfs, "rtmp_stream_has_video")
+		require.Equal(t, tc.hasVideo, hasVideo.Metric[0].GetGauge().GetValue(), tc.file)
+
+		hasAudio := findFamily(t, mfs, "rtmp_stream_has_audio")
+		require.Equal(t, tc.hasAudio, hasAudio.Metric[0].GetGauge().GetValue(), tc.file)
+	}
+}
+
+func TestExporter_ClientsOutOfSync(t *testing.T) {
+	setClients := func(s *rtmpstats.Stats) error {
+		s.Applications[0].Streams[0].Clients = []rtmpstats.Client{
+			{ID: "1", AVSync: -12, Publishing: true, EntriesCount: 1},
+			{ID: "2", AVSync: 250, EntriesCount: 1},
+			{ID: "3", AVSync: -300, EntriesCount: 1},
+			{ID: "4", AVSync: 100, EntriesCount: 1},
+			{ID: "5", AVSync: 0, EntriesCount: 1},
+			{ID: "6", AVSync: 400, EntriesCount: 3},
+		}
+		return nil
+	}
+
+	cfg := Config{StatsFiles: StringSlice{"testdata/stats.xml"}, AVSyncThresholdMs: 100}
+	mfs := gather(t, New(cfg, log.NewNopLogger(), setClients))
+
+	mf := findFamily(t, mfs, "rtmp_stream_clients_out_of_sync")
+	require.Len(t, mf.Metric, 1)
+	require.Equal(t, float64(5), mf.Metric[0].GetGauge().GetValue())
+}
+
+func TestExporter_ClientMetrics(t *testing.T) {
+	setClients := func(s *rtmpstats.Stats) error {
+		s.Applications[0].Streams[0].Clients = []rtmpstats.Client{
+			{ID: "1", DroppedFrames: 7, AVSync: -12, Publishing: true, EntriesCount: 1},
+			{ID: "2", DroppedFrames: 3, AVSync: 250, EntriesCount: 1},
+			{ID: "3", AVSync: -40, EntriesCount: 1},
+		}
+		return nil
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		mfs := gather(t, New(Config{StatsFiles: StringSlice{"testdata/stats.xml"}}, log.NewNopLogger(), setClients))
+		for _, mf := range mfs {
+			require.NotEqual(t, "rtmp_client_dropped_frames", mf.GetName())
+			require.NotEqual(t, "rtmp_client_avsync_ms", mf.GetName())
+		}
+	})
+
+	for _, maxClients := range []int{0, 1} {
+		t.Run("enabled max_clients="+strconv.Itoa(maxClients), func(t *testing.T) {
+			cfg := Config{StatsFiles: StringSlice{"testdata/stats.xml"}, ExposeClientMetrics: true, MaxClients: maxClients}
+			mfs := gather(t, New(cfg, log.NewNopLogger(), setClients))
+
+			clients := func(name string) map[string]float64 {
+				res := make(map[string]float64)
+				for _, m := range findFamily(t, mfs, name).Metric {
+					require.Equal(t, "streamName", labelValue(m, "stream"))
+					res[labelValue(m, "client_id")] = m.GetGauge().GetValue()
+				}
+				return res
+			}
+			require.Equal(t, map[string]float64{"1": 7, "2": 3, "3": 0}, clients("rtmp_client_dropped_frames"))
+			require.Equal(t, map[string]float64{"1": -12, "2": 250, "3": -40}, clients("rtmp_client_avsync_ms"))
+		})
+	}
+}
+
+func TestExporter_Outputs(t *testing.T) {
+	t.Run("absent", func(t *testing.T) {
+		mfs := gather(t, New(Config{StatsFiles: StringSlice{"testdata/stats.xml"}}, log.NewNopLogger()))
+		for _, mf := range mfs {
+			require.NotContains(t, []string{"rtmp_stream_hls_fragments", "rtmp_stream_hls_sequence", "rtmp_stream_dvr_size_bytes"}, mf.GetName())
+		}
+	})
+
+	t.Run("present", func(t *testing.T) {
+		setOutputs := func(s *rtmpstats.Stats) error {
+			s.Applications[0].Streams[0].HLS = &rtmpstats.HLS{Fragments: 5, Sequence: 83}
+			s.Applications[0].Streams[0].DVR = &rtmpstats.DVR{Size: 1024}
+			return nil
+		}
+		mfs := gather(t, New(Config{StatsFiles: StringSlice{"testdata/stats.xml"}}, log.NewNopLogger(), setOutputs))
+
+		require.Equal(t, float64(5), findFamily(t, mfs, "rtmp_stream_hls_fragments").Metric[0].GetGauge().GetValue())
+		require.Equal(t, float64(83), findFamily(t, mfs, "rtmp_stream_hls_sequence").Metric[0].GetGauge().GetValue())
+		require.Equal(t, float64(1024), findFamily(t, mfs, "rtmp_stream_dvr_size_bytes").Metric[0].GetGauge().GetValue())
+	})
+}
+
+func TestExporter_DropPublisherLabel(t *testing.T) {
+	tt := []struct {
+		drop   bool
+		expect []string
+	}{
+		{drop: false, expect: []string{"application", "publisher", "stream"}},
+		{drop: true, expect: []string{"application", "stream"}},
+	}
+
+	for _, tc := range tt {
+		cfg := Config{StatsFiles: StringSlice{"testdata/stats.xml"}, DropPublisherLabel: tc.drop}
+		mfs := gather(t, New(cfg, log.NewNopLogger()))
+
+		for _, name := range []string{"rtmp_stream_bitrate_in", "rtmp_stream_client_uptime_seconds", "rtmp_stream_publisher_timestamp_milliseconds"} {
+			var labels []string
+			for _, l := range findFamily(t, mfs, name).Metric[0].Label {
+				labels = append(labels, l.GetName())
+			}
+			require.Equal(t, tc.expect, labels, name)
+		}
+
+		info := findFamily(t, mfs, "rtmp_stream_info").Metric[0]
+		require.Equal(t, len(tc.expect)+6, len(info.Label))
+	}
+}
+
+func TestExporter_MultiplePublishers(t *testing.T) {
+	t.Run("publisher label", func(t *testing.T) {
+		cfg := Config{
+			StatsFiles:                StringSlice{"testdata/stats_multi_publisher.xml"},
+			RelayFlashVersionPrefixes: DefaultRelayFlashVersionPrefixes,
+		}
+		mfs := gather(t, New(cfg, log.NewNopLogger()))
+
+		timestamps := make(map[string]float64)
+		for _, m := range findFamily(t, mfs, "rtmp_stream_publisher_timestamp_milliseconds").Metric {
+			timestamps[labelValue(m, "publisher")] = m.GetGauge().GetValue()
+		}
+		require.Equal(t, map[string]float64{"1": 499599, "2": 498000}, timestamps)
+
+		relays := make(map[string]float64)
+		for _, m := range findFamily(t, mfs, "rtmp_stream_relay_active").Metric {
+			relays[labelValue(m, "publisher")] = m.GetGauge().GetValue()
+		}
+		require.Equal(t, map[string]float64{"1": 1, "2": 0}, relays)
+
+		// Stream-wide values are repeated for each publisher.
+		rx := findFamily(t, mfs, "rtmp_stream_bytes_read_total")
+		require.Len(t, rx.Metric, 2)
+		for _, m := range rx.Metric {
+			require.Equal(t, float64(129733847), m.GetCounter().GetValue())
+		}
+
+		// Metrics without a publisher label are only exposed once.
+		require.Len(t, findFamily(t, mfs, "rtmp_stream_clients_out_of_sync").Metric, 1)
+		require.Len(t, findFamily(t, mfs, "rtmp_client_count").Metric, 1)
+	})
+
+	t.Run("no publisher label", func(t *testing.T) {
+		cfg := Config{StatsFiles: StringSlice{"testdata/stats_multi_publisher.xml"}, DropPublisherLabel: true}
+		mfs := gather(t, New(cfg, log.NewNopLogger()))
+
+		mf := findFamily(t, mfs, "rtmp_stream_publisher_timestamp_milliseconds")
+		require.Len(t, mf.Metric, 1)
+		require.Equal(t, float64(499599), mf.Metric[0].GetGauge().GetValue())
+	})
+}
+
+func TestExporter_ClientCountMismatch(t *testing.T) {
+	tt := map[string]float64{
+		"testdata/stats.xml":           0,
+		"testdata/stats_truncated.xml": 2,
+	}
+
+	for file, expect := range tt {
+		mfs := gather(t, New(Config{StatsFiles: StringSlice{file}}, log.NewNopLogger()))
+		mf := findFamily(t, mfs, "rtmp_stream_client_count_mismatch")
+		require.Equal(t, expect, mf.Metric[0].GetGauge().GetValue(), file)
+	}
+}
+
+func TestExporter_ClientsMissingAddress(t *testing.T) {
+	tt := []struct {
+		file   string
+		expect float64
+	}{
+		{file: "testdata/stats.xml", expect: 0},
+		{file: "testdata/stats_missing_address.xml", expect: 1},
+	}
+
+	for _, tc := range tt {
+		mfs := gather(t, New(Config{StatsFiles: StringSlice{tc.file}}, log.NewNopLogger()))
+
+		mf := findFamily(t, mfs, "rtmp_stream_clients_missing_address")
+		require.Len(t, mf.Metric, 1)
+		require.Equal(t, tc.expect, mf.Metric[0].GetGauge().GetValue(), tc.file)
+	}
+}
+
+func TestExporter_IngestOnly(t *testing.T) {
+	unpublish := func(s *rtmpstats.Stats) error {
+		for i, app := range s.Applications {
+			if app.Name == "tenant-b" {
+				s.Applications[i].Streams[0].Publishing = false
+			}
+		}
+		return nil
+	}
+
+	cfg := Config{StatsFiles: StringSlice{"testdata/stats_apps.xml"}, IngestOnly: true}
+	mfs := gather(t, New(cfg, log.NewNopLogger(), unpublish))
+
+	var apps []string
+	for _, m := range findFamily(t, mfs, "rtmp_application_clients").Metric {
+		apps = append(apps, labelValue(m, "application"))
+	}
+	require.ElementsMatch(t, []string{"live", "tenant-a"}, apps)
+}
+
+func TestExporter_ApplicationClients(t *testing.T) {
+	mfs := gather(t, New(Config{StatsFiles: StringSlice{"testdata/stats_apps.xml"}}, log.NewNopLogger()))
+
+	actual := make(map[string]float64)
+	for _, m := range findFamily(t, mfs, "rtmp_application_clients").Metric {
+		actual[labelValue(m, "application")] = m.GetGauge().GetValue()
+	}
+
+	expect := map[string]float64{
+		"live":     2,
+		"tenant-a": 3,
+		"tenant-b": 1,
+		"playback": 0,
+	}
+	require.Equal(t, expect, actual)
+}
+
+func TestExporter_BytesUnaccounted(t *testing.T) {
+	tt := []struct {
+		name     string
+		mutators []rtmpstats.Mutator
+		expect   float64
+	}{
+		{name: "sample", expect: 130057972 - 129733847},
+		{
+			name: "clamped",
+			mutators: []rtmpstats.Mutator{func(s *rtmpstats.Stats) error {
+				s.BytesIn = 100
+				return nil
+			}},
+			expect: 0,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			mfs := gather(t, New(Config{StatsFiles: StringSlice{"testdata/stats.xml"}}, log.NewNopLogger(), tc.mutators...))
+
+			mf := findFamily(t, mfs, "rtmp_server_bytes_unaccounted")
+			require.Equal(t, tc.expect, mf.Metric[0].GetGauge().GetValue())
+		})
+	}
+}
+
+func TestExporter_EgressUtilization(t *testing.T) {
+	t.Run("cap", func(t *testing.T) {
+		cfg := Config{StatsFiles: StringSlice{"testdata/stats.xml"}, BandwidthCapBitsOut: 10000000}
+		mfs := gather(t, New(cfg, log.NewNopLogger()))
+
+		mf := findFamily(t, mfs, "rtmp_server_egress_utilization_ratio")
+		require.Equal(t, 7016072.0/10000000, mf.Metric[0].GetGauge().GetValue())
+	})
+
+	t.Run("no cap", func(t *testing.T) {
+		mfs := gather(t, New(Config{StatsFiles: StringSlice{"testdata/stats.xml"}}, log.NewNopLogger()))
+		for _, mf := range mfs {
+			require.NotEqual(t, "rtmp_server_egress_utilization_ratio", mf.GetName())
+		}
+	})
+}
+
+func TestExporter_EmptyApplications(t *testing.T) {
+	tt := []struct {
+		file   string
+		expect float64
+	}{
+		{file: "testdata/stats.xml", expect: 0},
+		{file: "testdata/stats_apps.xml", expect: 1},
+	}
+
+	for _, tc := range tt {
+		mfs := gather(t, New(Config{StatsFiles: StringSlice{tc.file}}, log.NewNopLogger()))
+		mf := findFamily(t, mfs, "rtmp_applications_empty")
+		require.Equal(t, tc.expect, mf.Metric[0].GetGauge().GetValue(), tc.file)
+	}
+}
+
+func TestExporter_DuplicateStreamNames(t *testing.T) {
+	tt := []struct {
+		file   string
+		expect float64
+	}{
+		{file: "testdata/stats.xml", expect: 0},
+		{file: "testdata/stats_apps.xml", expect: 1},
+	}
+
+	for _, tc := range tt {
+		var buf bytes.Buffer
+		cfg := Config{StatsFiles: StringSlice{tc.file}, WarnOnDuplicateStreamNames: true}
+		mfs := gather(t, New(cfg, log.NewLogfmtLogger(&buf)))
+
+		mf := findFamily(t, mfs, "rtmp_duplicate_stream_names")
+		require.Equal(t, tc.expect, mf.Metric[0].GetGauge().GetValue(), tc.file)
+
+		if tc.expect > 0 {
+			require.Contains(t, buf.String(), `msg="stream name used by multiple applications" stream=one applications=tenant-a,tenant-b`)
+		} else {
+			require.NotContains(t, buf.String(), "multiple applications")
+		}
+	}
+}
+
+func TestExporter_CardinalityLimits(t *testing.T) {
+	exceeded := func(mfs []*dto.MetricFamily) map[string]float64 {
+		res := make(map[string]float64)
+		for _, m := range findFamily(t, mfs, "rtmp_cardinality_limit_exceeded").Metric {
+			res[labelValue(m, "limit")] = m.GetGauge().GetValue()
+		}
+		return res
+	}
+
+	t.Run("streams", func(t *testing.T) {
+		cfg := Config{StatsFiles: StringSlice{"testdata/stats_apps.xml"}, MaxStreams: 2}
+		require.NoError(t, cfg.Validate())
+		mfs := gather(t, New(cfg, log.NewNopLogger()))
+
+		require.Len(t, findFamily(t, mfs, "rtmp_stream_bitrate_in").Metric, 2)
+		require.Equal(t, map[string]float64{"streams": 1, "clients": 0}, exceeded(mfs))
+
+		// Totals still cover the streams beyond the limit.
+		uncapped := gather(t, New(Config{StatsFiles: StringSlice{"testdata/stats_apps.xml"}}, log.NewNopLogger()))
+		for _, name := range []string{"rtmp_total_viewers", "rtmp_total_publishers"} {
+			require.Equal(t,
+				findFamily(t, uncapped, name).Metric[0].GetGauge().GetValue(),
+				findFamily(t, mfs, name).Metric[0].GetGauge().GetValue(),
+				name,
+			)
+		}
+	})
+
+	t.Run("clients", func(t *testing.T) {
+		cfg := Config{StatsFiles: StringSlice{"testdata/stats.xml"}, MaxClients: 1}
+		mfs := gather(t, New(cfg, log.NewNopLogger()))
+
+		require.Len(t, findFamily(t, mfs, "rtmp_client_count").Metric, 1)
+		require.Equal(t, float64(3), findFamily(t, mfs, "rtmp_total_viewers").Metric[0].GetGauge().GetValue())
+		require.Equal(t, map[string]float64{"streams": 0, "clients": 1}, exceeded(mfs))
+	})
+
+	t.Run("within limits", func(t *testing.T) {
+		cfg := Config{StatsFiles: StringSlice{"testdata/stats_apps.xml"}, MaxStreams: 4, MaxClients: 100}
+		mfs := gather(t, New(cfg, log.NewNopLogger()))
+
+		require.Len(t, findFamily(t, mfs, "rtmp_stream_bitrate_in").Metric, 4)
+		require.Equal(t, map[string]float64{"streams": 0, "clients": 0}, exceeded(mfs))
+	})
+
+	cfg := Config{MaxStreams: -1}
+	require.Error(t, cfg.Validate())
+}
+
+func TestExporter_ClientsBySubnet(t *testing.T) {
+	setClients := func(s *rtmpstats.Stats) error {
+		s.Applications[0].Streams[0].Clients = []rtmpstats.Client{
+			{ID: "publisher", Address: "10.0.0.1", Publishing: true, EntriesCount: 1},
+			{ID: "1", Address: "192.168.1.10", EntriesCount: 1},
+			{ID: "2", Address: "192.168.1.200:50000", EntriesCount: 2},
+			{ID: "3", Address: "192.168.2.10", EntriesCount: 1},
+			{ID: "4", Address: "[2001:db8:1:2::1]:1935", EntriesCount: 1},
+			{ID: "5", Address: "2001:db8:1:3::1", EntriesCount: 1},
+			{ID: "6", Address: "", EntriesCount: 1},
+		}
+		return nil
+	}
+
+	cfg := Config{StatsFiles: StringSlice{"testdata/stats.xml"}, ClientSubnetPrefix: 24, ClientSubnetPrefixV6: 48}
+	require.NoError(t, cfg.Validate())
+	mfs := gather(t, New(cfg, log.NewNopLogger(), setClients))
+
+	actual := make(map[string]float64)
+	for _, m := range findFamily(t, mfs, "rtmp_clients_by_subnet").Metric {
+		actual[labelValue(m, "subnet")] = m.GetGauge().GetValue()
+	}
+	require.Equal(t, map[string]float64{
+		"192.168.1.0/24":  3,
+		"192.168.2.0/24":  1,
+		"2001:db8:1::/48": 2,
+		"unknown":         1,
+	}, actual)
+
+	cfg.ClientSubnetPrefix = 33
+	require.Error(t, cfg.Validate())
+}
+
+func TestExporter_ApplicationStreams(t *testing.T) {
+	mfs := gather(t, New(Config{StatsFiles: StringSlice{"testdata/stats_apps.xml"}}, log.NewNopLogger()))
+
+	values := func(name string) map[string]float64 {
+		res := make(map[string]float64)
+		for _, m := range findFamily(t, mfs, name).Metric {
+			res[labelValue(m, "application")] = m.GetGauge().GetValue()
+		}
+		return res
+	}
+
+	expectActive := map[string]float64{"live": 1, "tenant-a": 1, "tenant-b": 1, "playback": 0}
+	require.Equal(t, expectActive, values("rtmp_application_active_streams"))
+
+	expectTotal := map[string]float64{"live": 1, "tenant-a": 2, "tenant-b": 1, "playback": 0}
+	require.Equal(t, expectTotal, values("rtmp_application_total_streams"))
+}
+
+func TestExporter_StreamsByResolution(t *testing.T) {
+	mfs := gather(t, New(Config{StatsFiles: StringSlice{"testdata/stats_apps.xml"}}, log.NewNopLogger()))
+
+	counts := make(map[string]float64)
+	for _, m := range findFamily(t, mfs, "rtmp_streams_by_resolution").Metric {
+		counts[labelValue(m, "application")+"/"+labelValue(m, "resolution")] = m.GetGauge().GetValue()
+	}
+	require.Equal(t, map[string]float64{
+		"live/1920x1080":     1,
+		"tenant-a/1280x720":  1,
+		"tenant-a/unknown":   1,
+		"tenant-b/1920x1080": 1,
+	}, counts)
+}
+
+func TestExporter_RelayActive(t *testing.T) {
+	tt := []struct {
+		prefixes Strings
+		expect   float64
+	}{
+		{prefixes: DefaultRelayFlashVersionPrefixes, expect: 1},
+		{prefixes: Strings{"ngx-local-relay"}, expect: 0},
+		{prefixes: nil, expect: 0},
+	}
+
+	for _, tc := range tt {
+		cfg := Config{StatsFiles: StringSlice{"testdata/stats.xml"}, RelayFlashVersionPrefixes: tc.prefixes}
+		mfs := gather(t, New(cfg, log.NewNopLogger()))
+
+		mf := findFamily(t, mfs, "rtmp_stream_relay_active")
+		require.Len(t, mf.Metric, 1)
+		require.Equal(t, tc.expect, mf.Metric[0].GetGauge().GetValue(), tc.prefixes)
+	}
+}
+
+func TestExporter_StartTime(t *testing.T) {
+	before := time.Now()
+	e := New(Config{StatsFiles: StringSlice{"testdata/missing.xml"}}, log.NewNopLogger())
+	after := time.Now()
+
+	mfs := gather(t, e)
+	start := findFamily(t, mfs, "rtmp_exporter_start_time_seconds").Metric[0].GetGauge().GetValue()
+	require.GreaterOrEqual(t, start, float64(before.Unix()))
+	require.LessOrEqual(t, start, float64(after.Unix()+1))
+
+	again := findFamily(t, gather(t, e), "rtmp_exporter_start_time_seconds").Metric[0].GetGauge().GetValue()
+	require.Equal(t, start, again)
+}
+
+func TestExporter_ConstantLabels(t *testing.T) {
+	cfg := Config{
+		StatsFiles:     StringSlice{"testdata/stats.xml"},
+		ConstantLabels: Labels{"region": "us-east"},
+	}
+	mfs := gather(t, New(cfg, log.NewNopLogger()))
+	require.NotEmpty(t, mfs)
+
+	for _, mf := range mfs {
+		for _, m := range mf.Metric {
+			require.Equal(t, "us-east", labelValue(m, "region"), mf.GetName())
+		}
+	}
+}
+
+func TestConfig_ValidateConstantLabels(t *testing.T) {
+	for _, name := range []string{"application", "stream", "client_id"} {
+		cfg := Config{ConstantLabels: Labels{name: "x"}}
+		require.Error(t, cfg.Validate(), name)
+	}
+
+	// Every label set by the exporter must be rejected as a constant label.
+	cfg := Config{
+		StatsFiles:          StringSlice{"testdata/stats.xml"},
+		PIDLabel:            true,
+		ExposeClientMetrics: true,
+		ClientSubnetPrefix:  24,
+		ConstantLabels:      Labels{"region": "us-east"},
+	}
+	require.NoError(t, cfg.Validate())
+	for _, mf := range gather(t, New(cfg, log.NewNopLogger())) {
+		for _, m := range mf.Metric {
+			for _, l := range m.Label {
+				if l.GetName() != "region" {
+					require.Contains(t, variableLabelNames, l.GetName(), mf.GetName())
+				}
+			}
+		}
+	}
+}
+
+func TestExporter_BitrateOverhead(t *testing.T) {
+	t.Run("sample", func(t *testing.T) {
+		mfs := gather(t, New(Config{StatsFiles: StringSlice{"testdata/stats.xml"}}, log.NewNopLogger()))
+
+		require.Equal(t, float64(2333120), findFamily(t, mfs, "rtmp_stream_bitrate_av_sum").Metric[0].GetGauge().GetValue())
+		require.Equal(t, float64(8), findFamily(t, mfs, "rtmp_stream_bitrate_overhead").Metric[0].GetGauge().GetValue())
+	})
+
+	t.Run("clamped", func(t *testing.T) {
+		setBitrates := func(s *rtmpstats.Stats) error {
+			s.Applications[0].Streams[0].BitrateIn = 100
+			s.Applications[0].Streams[0].BitrateVideo = 90
+			s.Applications[0].Streams[0].BitrateAudio = 20
+			return nil
+		}
+		mfs := gather(t, New(Config{StatsFiles: StringSlice{"testdata/stats.xml"}}, log.NewNopLogger(), setBitrates))
+
+		require.Equal(t, float64(110), findFamily(t, mfs, "rtmp_stream_bitrate_av_sum").Metric[0].GetGauge().GetValue())
+		require.Equal(t, float64(0), findFamily(t, mfs, "rtmp_stream_bitrate_overhead").Metric[0].GetGauge().GetValue())
+	})
+}
+
+func TestExporter_DroppedFramesRatio(t *testing.T) {
+	tt := []struct {
+		name      string
+		framerate int
+		clients   []rtmpstats.Client
+		expect    float64
+	}{
+		{
+			name:      "dropped",
+			framerate: 30,
+			clients: []rtmpstats.Client{
+				{ID: "1", Uptime: 10 * time.Second, DroppedFrames: 15},
+				{ID: "2", Uptime: 10 * time.Second, DroppedFrames: 45},
+			},
+			expect: 0.1,
+		},
+		{
+			name:      "no framerate",
+			framerate: 0,
+			clients:   []rtmpstats.Client{{ID: "1", Uptime: 10 * time.Second, DroppedFrames: 15}},
+			expect:    0,
+		},
+		{
+			name:      "no uptime",
+			framerate: 30,
+			clients:   []rtmpstats.Client{{ID: "1", DroppedFrames: 15}},
+			expect:    0,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			setStream := func(s *rtmpstats.Stats) error {
+				s.Applications[0].Streams[0].VideoFramerate = tc.framerate
+				s.Applications[0].Streams[0].Clients = tc.clients
+				return nil
+			}
+			mfs := gather(t, New(Config{StatsFiles: StringSlice{"testdata/stats.xml"}}, log.NewNopLogger(), setStream))
+
+			mf := findFamily(t, mfs, "rtmp_stream_dropped_frames_ratio")
+			require.InDelta(t, tc.expect, mf.Metric[0].GetGauge().GetValue(), 0.0001)
+		})
+	}
+}
+
+func TestExporter_StuckClients(t *testing.T) {
+	var scrape int
+	setTimestamps := func(s *rtmpstats.Stats) error {
+		scrape++
+
+		stream := &s.Applications[0].Streams[0]
+		stream.Clients = []rtmpstats.Client{
+			{ID: "stuck", Timestamp: time.Second, EntriesCount: 1},
+			{ID: "advancing", Timestamp: time.Duration(scrape) * time.Second, EntriesCount: 1},
+			{ID: "publisher", Timestamp: time.Second, Publishing: true, EntriesCount: 1},
+		}
+		if scrape > 2 {
+			// Drop the stuck client to ensure it gets evicted.
+			stream.Clients = stream.Clients[1:]
+		}
+		return nil
+	}
+
+	e := New(Config{StatsFiles: StringSlice{"testdata/stats.xml"}, DetectStuckClients: true}, log.NewNopLogger(), setTimestamps)
+	stuckClients := func() float64 {
+		mf := findFamily(t, gather(t, e), "rtmp_stream_stuck_clients")
+		return mf.Metric[0].GetGauge().GetValue()
+	}
+
+	require.Equal(t, 0.0, stuckClients(), "first scrape has nothing to compare against")
+	require.Equal(t, 1.0, stuckClients())
+	require.Equal(t, 0.0, stuckClients())
+	require.NotContains(t, e.state.clientTimestamps, "live/streamName/stuck")
+
+	e.Reset()
+	require.Empty(t, e.state.clientTimestamps)
+}
+
+func TestExporter_BitrateSmoothing(t *testing.T) {
+	bitrates := []int64{1000, 2000, 0}
+	var scrape int
+	setBitrate := func(s *rtmpstats.Stats) error {
+		s.BitrateIn = bitrates[scrape]
+		s.Applications[0].Streams[0].BitrateIn = bitrates[scrape]
+		scrape++
+		return nil
+	}
+
+	cfg := Config{StatsFiles: StringSlice{"testdata/stats.xml"}, BitrateSmoothing: 0.25}
+	require.NoError(t, cfg.Validate())
+	e := New(cfg, log.NewNopLogger(), setBitrate)
+
+	// 1000, then 0.25*2000 + 0.75*1000, then 0.25*0 + 0.75*1250.
+	for _, expect := range []float64{1000, 1250, 937.5} {
+		mfs := gather(t, e)
+
+		server := findFamily(t, mfs, "rtmp_server_bitrate_in_smoothed")
+		require.Equal(t, expect, server.Metric[0].GetGauge().GetValue())
+
+		stream := findFamily(t, mfs, "rtmp_stream_bitrate_in_smoothed")
+		require.Equal(t, expect, stream.Metric[0].GetGauge().GetValue())
+	}
+
+	e.Reset()
+	require.Empty(t, e.state.smoothedBitrates)
+
+	cfg.BitrateSmoothing = 1.5
+	require.Error(t, cfg.Validate())
+}
+
+func TestExporter_AVBitrateRatio(t *testing.T) {
+	t.Run("sample", func(t *testing.T) {
+		mfs := gather(t, New(Config{StatsFiles: StringSlice{"testdata/stats.xml"}}, log.NewNopLogger()))
+
+		ratio := findFamily(t, mfs, "rtmp_stream_av_bitrate_ratio")
+		require.Equal(t, float64(106920)/float64(2226200), ratio.Metric[0].GetGauge().GetValue())
+	})
+
+	t.Run("no video", func(t *testing.T) {
+		setBitrates := func(s *rtmpstats.Stats) error {
+			s.Applications[0].Streams[0].BitrateVideo = 0
+			return nil
+		}
+		mfs := gather(t, New(Config{StatsFiles: StringSlice{"testdata/stats.xml"}}, log.NewNopLogger(), setBitrates))
+
+		ratio := findFamily(t, mfs, "rtmp_stream_av_bitrate_ratio")
+		require.Equal(t, float64(0), ratio.Metric[0].GetGauge().GetValue())
+	})
+}
+
+func TestExporter_BitrateVsLevel(t *testing.T) {
+	setVideo := func(level float64, bitrate int64) rtmpstats.Mutator {
+		return func(s *rtmpstats.Stats) error {
+			stream := &s.Applications[0].Streams[0]
+			stream.VideoLevel, stream.BitrateVideo = level, bitrate
+			return nil
+		}
+	}
+	setProfile := func(profile string) rtmpstats.Mutator {
+		return func(s *rtmpstats.Stats) error {
+			s.Applications[0].Streams[0].VideoProfile = profile
+			return nil
+		}
+	}
+
+	tt := []struct {
+		name      string
+		mutators  []rtmpstats.Mutator
+		overrides LevelBitrates
+		expect    float64
+	}{
+		{name: "sample", expect: 2226200.0 / 25000000},
+		{name: "above level", mutators: []rtmpstats.Mutator{setVideo(3.1, 26250000)}, expect: 1.5},
+		{name: "level 5.1", mutators: []rtmpstats.Mutator{setVideo(5.1, 75000000)}, expect: 0.25},
+		{name: "main profile", mutators: []rtmpstats.Mutator{setProfile("Main"), setVideo(3.1, 21000000)}, expect: 1.5},
+		{name: "high 10 profile", mutators: []rtmpstats.Mutator{setProfile("High 10"), setVideo(4, 30000000)}, expect: 0.5},
+		{name: "overridden", overrides: LevelBitrates{4: 10000}, expect: 2226200.0 / 12500000},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := Config{StatsFiles: StringSlice{"testdata/stats.xml"}, LevelMaxBitrates: tc.overrides}
+			mfs := gather(t, New(cfg, log.NewNopLogger(), tc.mutators...))
+
+			mf := findFamily(t, mfs, "rtmp_stream_bitrate_vs_level_ratio")
+			require.InDelta(t, tc.expect, mf.Metric[0].GetGauge().GetValue(), 1e-9)
+		})
+	}
+
+	t.Run("unknown level", func(t *testing.T) {
+		mfs := gather(t, New(Config{StatsFiles: StringSlice{"testdata/stats.xml"}}, log.NewNopLogger(), setVideo(9, 1000)))
+		for _, mf := range mfs {
+			require.NotEqual(t, "rtmp_stream_bitrate_vs_level_ratio", mf.GetName())
+		}
+	})
+}
+
+func TestExporter_MutatorInfo(t *testing.T) {
+	mutators := []rtmpstats.NamedMutator{
+		{Type: "rename_streams", Mutator: rtmpstats.WithStreamMapper(strings.ToUpper)},
+		{Type: "strip_stream_meta", Mutator: rtmpstats.WithoutStreamMeta()},
+	}
+	mfs := gather(t, NewWithNamedMutators(Config{StatsFiles: StringSlice{"testdata/stats.xml"}}, log.NewNopLogger(), mutators))
+
+	mf := findFamily(t, mfs, "rtmp_mutator_info")
+	var pipeline []string
+	for _, m := range mf.Metric {
+		pipeline = append(pipeline, labelValue(m, "index")+"="+labelValue(m, "type"))
+	}
+	require.Equal(t, []string{"0=rename_streams", "1=strip_stream_meta"}, pipeline)
+
+	t.Run("custom", func(t *testing.T) {
+		mfs := gather(t, New(Config{StatsFiles: StringSlice{"testdata/stats.xml"}}, log.NewNopLogger(), rtmpstats.WithoutStreamMeta()))
+
+		mf := findFamily(t, mfs, "rtmp_mutator_info")
+		require.Len(t, mf.Metric, 1)
+		require.Equal(t, CustomMutatorType, labelValue(mf.Metric[0], "type"))
+	})
+}
+
+func TestExporter_ActiveClients(t *testing.T) {
+	tt := []struct {
+		file                     string
+		expectActive, expectIdle float64
+	}{
+		{file: "testdata/stats.xml", expectActive: 4, expectIdle: 0},
+		{file: "testdata/stats_inactive_clients.xml", expectActive: 2, expectIdle: 2},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.file, func(t *testing.T) {
+			mfs := gather(t, New(Config{StatsFiles: StringSlice{tc.file}}, log.NewNopLogger()))
+
+			active := findFamily(t, mfs, "rtmp_stream_active_clients")
+			require.Equal(t, tc.expectActive, active.Metric[0].GetGauge().GetValue())
+			inactive := findFamily(t, mfs, "rtmp_stream_inactive_clients")
+			require.Equal(t, tc.expectIdle, inactive.Metric[0].GetGauge().GetValue())
+		})
+	}
+
+	t.Run("aggregated", func(t *testing.T) {
+		// Both inactive viewers are aggregated into a single client.
+		aggregate := rtmpstats.WithClientMapper(func(_ string, id string) string {
+			if id == "51" || id == "15" {
+				return "idle"
+			}
+			return id
+		})
+		mfs := gather(t, New(Config{StatsFiles: StringSlice{"testdata/stats_inactive_clients.xml"}}, log.NewNopLogger(), aggregate))
+
+		active := findFamily(t, mfs, "rtmp_stream_active_clients")
+		require.Equal(t, float64(2), active.Metric[0].GetGauge().GetValue())
+		inactive := findFamily(t, mfs, "rtmp_stream_inactive_clients")
+		require.Equal(t, float64(2), inactive.Metric[0].GetGauge().GetValue())
+	})
+}
+
+func TestExporter_UseDataTimestamp(t *testing.T) {
+	buf, err := ioutil.ReadFile("testdata/stats.xml")
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "rtmp_exporter")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "stats.xml")
+	require.NoError(t, ioutil.WriteFile(path, buf, 0644))
+	modTime := time.Date(2020, 7, 12, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+
+	t.Run("enabled", func(t *testing.T) {
+		mfs := gather(t, New(Config{StatsFiles: StringSlice{path}, UseDataTimestamp: true}, log.NewNopLogger()))
+
+		bitrate := findFamily(t, mfs, "rtmp_stream_bitrate_in")
+		require.Equal(t, modTime.UnixNano()/1e6, bitrate.Metric[0].GetTimestampMs())
+
+		// Exporter metrics are always at scrape time.
+		scrapes := findFamily(t, mfs, "rtmp_scrapes_total")
+		require.Nil(t, scrapes.Metric[0].TimestampMs)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		mfs := gather(t, New(Config{StatsFiles: StringSlice{path}}, log.NewNopLogger()))
+
+		bitrate := findFamily(t, mfs, "rtmp_stream_bitrate_in")
+		require.Nil(t, bitrate.Metric[0].TimestampMs)
+	})
+}
+
+func TestExporter_SubscribersAndPublishers(t *testing.T) {
+	tt := []struct {
+		file                                string
+		expectSubscribers, expectPublishers float64
+	}{
+		// Derived from the listed clients.
+		{file: "testdata/stats.xml", expectSubscribers: 3, expectPublishers: 1},
+		// Reported by the server, with a truncated list of clients.
+		{file: "testdata/stats_nclients_breakdown.xml", expectSubscribers: 27, expectPublishers: 1},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.file, func(t *testing.T) {
+			mfs := gather(t, New(Config{StatsFiles: StringSlice{tc.file}}, log.NewNopLogger()))
+
+			subscribers := findFamily(t, mfs, "rtmp_stream_subscribers")
+			require.Equal(t, tc.expectSubscribers, subscribers.Metric[0].GetGauge().GetValue())
+			publishers := findFamily(t, mfs, "rtmp_stream_publishers")
+			require.Equal(t, tc.expectPublishers, publishers.Metric[0].GetGauge().GetValue())
+
+			viewers := findFamily(t, mfs, "rtmp_total_viewers")
+			require.Equal(t, tc.expectSubscribers, viewers.Metric[0].GetGauge().GetValue())
+		})
+	}
+}
+
+func TestExporter_TrackInfo(t *testing.T) {
+	addTracks := func(s *rtmpstats.Stats) error {
+		stream := &s.Applications[0].Streams[0]
+		stream.VideoTracks = append(stream.VideoTracks, rtmpstats.VideoTrack{Codec: "H264", Profile: "Main"})
+		stream.AudioTracks = append(stream.AudioTracks, rtmpstats.AudioTrack{Codec: "MP3"})
+		return nil
+	}
+	mfs := gather(t, New(Config{StatsFiles: StringSlice{"testdata/stats.xml"}}, log.NewNopLogger(), addTracks))
+
+	mf := findFamily(t, mfs, "rtmp_stream_track_info")
+	var tracks []string
+	for _, m := range mf.Metric {
+		tracks = append(tracks, strings.Join([]string{
+			labelValue(m, "type"), labelValue(m, "index"), labelValue(m, "codec"), labelValue(m, "profile"),
+		}, "/"))
+	}
+	require.ElementsMatch(t, []string{
+		"video/0/H264/High",
+		"video/1/H264/Main",
+		"audio/0/AAC/LC",
+		"audio/1/MP3/",
+	}, tracks)
+}
+
+func TestExporter_ClientUptimeAvg(t *testing.T) {
+	t.Run("sample", func(t *testing.T) {
+		mfs := gather(t, New(Config{StatsFiles: StringSlice{"testdata/stats.xml"}}, log.NewNopLogger()))
+
+		// Mean of the three viewers, excluding the publisher.
+		avg := findFamily(t, mfs, "rtmp_stream_client_uptime_avg_seconds")
+		require.Equal(t, (36.310+371.856+496.931)/3, avg.Metric[0].GetGauge().GetValue())
+	})
+
+	t.Run("no viewers", func(t *testing.T) {
+		setClients := func(s *rtmpstats.Stats) error {
+			s.Applications[0].Streams[0].Clients = []rtmpstats.Client{{ID: "1", Publishing: true, Uptime: time.Minute, EntriesCount: 1}}
+			return nil
+		}
+		mfs := gather(t, New(Config{StatsFiles: StringSlice{"testdata/stats.xml"}}, log.NewNopLogger(), setClients))
+
+		avg := findFamily(t, mfs, "rtmp_stream_client_uptime_avg_seconds")
+		require.Equal(t, float64(0), avg.Metric[0].GetGauge().GetValue())
+	})
+}
+
+func TestExporter_BitrateStddev(t *testing.T) {
+	bitrates := []int64{1000, 3000, 2000, 2000}
+	var scrape int
+	setBitrate := func(s *rtmpstats.Stats) error {
+		s.Applications[0].Streams[0].BitrateIn = bitrates[scrape]
+		scrape++
+		return nil
+	}
+
+	cfg := Config{StatsFiles: StringSlice{"testdata/stats.xml"}, BitrateWindow: 3}
+	require.NoError(t, cfg.Validate())
+	e := New(cfg, log.NewNopLogger(), setBitrate)
+
+	// The last sample evicts 1000 from the window, leaving 3000, 2000, 2000.
+	for _, expect := range []float64{0, 1000, math.Sqrt(2e6 / 3), math.Sqrt(2e6 / 9)} {
+		mfs := gather(t, e)
+
+		stddev := findFamily(t, mfs, "rtmp_stream_bitrate_in_stddev")
+		require.InDelta(t, expect, stddev.Metric[0].GetGauge().GetValue(), 1e-9)
+	}
+
+	e.Reset()
+	require.Empty(t, e.state.bitrateWindows)
+
+	cfg.BitrateWindow = -1
+	require.Error(t, cfg.Validate())
+}
+
+func TestExporter_ChangeLog(t *testing.T) {
+	type streamState struct {
+		bitrate       int64
+		width, height int
+	}
+	scrapes := []map[string]streamState{
+		{"a": {bitrate: 1000}, "b": {bitrate: 1000}},
+		{"a": {bitrate: 1200}, "b": {bitrate: 3000}},
+		{"a": {bitrate: 1200, width: 1280, height: 720}, "c": {bitrate: 1000}},
+	}
+	var scrape int
+	setStreams := func(s *rtmpstats.Stats) error {
+		var streams []rtmpstats.Stream
+		for _, name := range []string{"a", "b", "c"} {
+			if state, ok := scrapes[scrape][name]; ok {
+				streams = append(streams, rtmpstats.Stream{
+					Name:        name,
+					BitrateIn:   state.bitrate,
+					VideoWidth:  state.width,
+					VideoHeight: state.height,
+				})
+			}
+		}
+		s.Applications[0].Streams = streams
+		scrape++
+		return nil
+	}
+
+	var buf bytes.Buffer
+	cfg := Config{StatsFiles: StringSlice{"testdata/stats.xml"}, ChangeLog: true, ChangeLogBitrateThreshold: 0.5}
+	require.NoError(t, cfg.Validate())
+	e := New(cfg, log.NewLogfmtLogger(&buf), setStreams)
+
+	expect := [][]string{
+		nil,
+		{`msg="stream bitrate changed" application=live stream=b old=1000 new=3000`},
+		{
+			`msg="stream resolution changed" application=live stream=a old=unknown new=1280x720`,
+			`msg="stream appeared" application=live stream=c bitrate_in=1000 resolution=unknown`,
+			`msg="stream disappeared" application=live stream=b`,
+		},
+	}
+	for _, lines := range expect {
+		buf.Reset()
+		gather(t, e)
+
+		out := buf.String()
+		for _, line := range lines {
+			require.Contains(t, out, line)
+		}
+		require.Equal(t, len(lines), strings.Count(out, "\n"), out)
+	}
+
+	e.Reset()
+	require.Nil(t, e.state.streamSnapshots)
+
+	cfg.ChangeLogBitrateThreshold = -1
+	require.Error(t, cfg.Validate())
+}
+
+func TestExporter_ObservedAge(t *testing.T) {
+	present := true
+	toggleStream := func(s *rtmpstats.Stats) error {
+		if !present {
+			s.Applications[0].Streams = nil
+		}
+		return nil
+	}
+
+	e := New(Config{StatsFiles: StringSlice{"testdata/stats.xml"}, TrackObservedAge: true}, log.NewNopLogger(), toggleStream)
+	age := func() float64 {
+		return findFamily(t, gather(t, e), "rtmp_stream_observed_age_seconds").Metric[0].GetGauge().GetValue()
+	}
+
+	require.Equal(t, float64(0), age())
+	time.Sleep(10 * time.Millisecond)
+	require.True(t, age() >= 0.01, "age should increase between scrapes")
+
+	// A stream that disappears is seen for the first time again when it
+	// comes back.
+	present = false
+	gather(t, e)
+	require.Empty(t, e.state.streamFirstSeen)
+	present = true
+	require.Equal(t, float64(0), age())
+
+	e.Reset()
+	require.Empty(t, e.state.streamFirstSeen)
+}
+
+func TestExporter_ClientChurn(t *testing.T) {
+	scrapes := [][]string{
+		{"a", "b"},
+		{"b", "c", "d"},
+		{"d"},
+	}
+	var scrape int
+	setClients := func(s *rtmpstats.Stats) error {
+		clients := []rtmpstats.Client{{ID: "publisher", Publishing: true, EntriesCount: 1}}
+		for _, id := range scrapes[scrape] {
+			clients = append(clients, rtmpstats.Client{ID: id, EntriesCount: 1})
+		}
+		s.Applications[0].Streams[0].Clients = clients
+		scrape++
+		return nil
+	}
+
+	e := New(Config{StatsFiles: StringSlice{"testdata/stats.xml"}, TrackClientChurn: true}, log.NewNopLogger(), setClients)
+	for _, expect := range []struct{ joined, left float64 }{
+		{joined: 0, left: 0},
+		{joined: 2, left: 1},
+		{joined: 2, left: 3},
+	} {
+		mfs := gather(t, e)
+
+		joined := findFamily(t, mfs, "rtmp_stream_clients_joined_total")
+		require.Equal(t, expect.joined, joined.Metric[0].GetCounter().GetValue())
+
+		left := findFamily(t, mfs, "rtmp_stream_clients_left_total")
+		require.Equal(t, expect.left, left.Metric[0].GetCounter().GetValue())
+	}
+
+	e.Reset()
+	require.Empty(t, e.state.streamChurn)
+}
+
+func TestExporter_ClientChurn_RenderText(t *testing.T) {
+	setClients := func(s *rtmpstats.Stats) error {
+		s.Applications[0].Streams[0].Clients = []rtmpstats.Client{{ID: "a", EntriesCount: 1}, {ID: "b", EntriesCount: 1}}
+		return nil
+	}
+	e := New(Config{StatsFiles: StringSlice{"testdata/stats.xml"}, TrackClientChurn: true}, log.NewNopLogger(), setClients)
+
+	counters := func() (joined, left float64) {
+		mfs := gather(t, e)
+		joined = findFamily(t, mfs, "rtmp_stream_clients_joined_total").Metric[0].GetCounter().GetValue()
+		left = findFamily(t, mfs, "rtmp_stream_clients_left_total").Metric[0].GetCounter().GetValue()
+		return joined, left
+	}
+
+	joined, left := counters()
+
+	s, err := e.Stats(context.Background())
+	require.NoError(t, err)
+	s.Applications[0].Streams[0].Clients = []rtmpstats.Client{{ID: "x", EntriesCount: 1}, {ID: "y", EntriesCount: 1}, {ID: "z", EntriesCount: 1}}
+	require.NoError(t, e.RenderText(s, ioutil.Discard))
+
+	afterJoined, afterLeft := counters()
+	require.Equal(t, joined, afterJoined)
+	require.Equal(t, left, afterLeft)
+}
+
+func TestExporter_ClientChurn_Overlapping(t *testing.T) {
+	// Every scrape sees a single new viewer, so each scrape after the first
+	// counts one join and one leave.
+	var next int
+	setClients := func(s *rtmpstats.Stats) error {
+		next++
+		s.Applications[0].Streams[0].Clients = []rtmpstats.Client{{ID: strconv.Itoa(next), EntriesCount: 1}}
+		return nil
+	}
+
+	e := New(Config{StatsFiles: StringSlice{"testdata/stats.xml"}, TrackClientChurn: true}, log.NewNopLogger(), setClients)
+	collect := func() chan prometheus.Metric {
+		s, err := e.Stats(context.Background())
+		require.NoError(t, err)
+
+		ch := make(chan prometheus.Metric)
+		go func() {
+			defer close(ch)
+			e.collectStats(ch, s)
+		}()
+		return ch
+	}
+	drain := func(ch chan prometheus.Metric) {
+		for range ch {
+		}
+	}
+	drain(collect())
+
+	// Pause the first scrape once it sends per-application metrics, after
+	// the previous state was read, and give an overlapping scrape the chance
+	// to finish before it.
+	first := collect()
+	for m := range first {
+		var pb dto.Metric
+		require.NoError(t, m.Write(&pb))
+		if labelValue(&pb, "application") != "" {
+			break
+		}
+	}
+	second := collect()
+	secondDone := make(chan struct{})
+	go func() {
+		defer close(secondDone)
+		drain(second)
+	}()
+	select {
+	case <-secondDone:
+	case <-time.After(100 * time.Millisecond):
+	}
+	drain(first)
+	<-secondDone
+
+	mfs := gather(t, e)
+	require.Equal(t, float64(3), findFamily(t, mfs, "rtmp_stream_clients_joined_total").Metric[0].GetCounter().GetValue())
+	require.Equal(t, float64(3), findFamily(t, mfs, "rtmp_stream_clients_left_total").Metric[0].GetCounter().GetValue())
+}
+
+func TestExporter_UptimeResolution(t *testing.T) {
+	tt := []struct {
+		resolution    time.Duration
+		streamUptime  float64
+		clientUptimes []float64
+	}{
+		{resolution: 0, streamUptime: 500.003, clientUptimes: []float64{36.31, 371.856, 496.931}},
+		{resolution: 100 * time.Millisecond, streamUptime: 500, clientUptimes: []float64{36.3, 371.8, 496.9}},
+		{resolution: time.Second, streamUptime: 500, clientUptimes: []float64{36, 371, 496}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.resolution.String(), func(t *testing.T) {
+			mfs := gather(t, New(Config{StatsFiles: StringSlice{"testdata/stats.xml"}, UptimeResolution: tc.resolution}, log.NewNopLogger()))
+
+			mf := findFamily(t, mfs, "rtmp_stream_uptime_seconds")
+			require.InDelta(t, tc.streamUptime, mf.Metric[0].GetCounter().GetValue(), 1e-9)
+
+			var clientUptimes []float64
+			for _, m := range findFamily(t, mfs, "rtmp_client_uptime_seconds").Metric {
+				clientUptimes = append(clientUptimes, m.GetCounter().GetValue())
+			}
+			require.ElementsMatch(t, tc.clientUptimes, clientUptimes)
+		})
+	}
+}
+
+func TestExporter_UnifiedByteMetrics(t *testing.T) {
+	mfs := gather(t, New(Config{StatsFiles: StringSlice{"testdata/stats.xml"}, UnifiedByteMetrics: true}, log.NewNopLogger()))
+
+	values := make(map[string]float64)
+	for _, m := range findFamily(t, mfs, "rtmp_bytes_total").Metric {
+		values[labelValue(m, "direction")] = m.GetCounter().GetValue()
+	}
+	require.Equal(t, map[string]float64{"in": 130057972, "out": 239470507}, values)
+
+	for _, mf := range mfs {
+		require.NotEqual(t, "rtmp_server_bytes_read_total", mf.GetName())
+		require.NotEqual(t, "rtmp_server_bytes_sent_total", mf.GetName())
+	}
+
+	mfs = gather(t, New(Config{StatsFiles: StringSlice{"testdata/stats.xml"}}, log.NewNopLogger()))
+	for _, mf := range mfs {
+		require.NotEqual(t, "rtmp_bytes_total", mf.GetName())
+	}
+}
+
+func TestExporter_PIDLabel(t *testing.T) {
+	tt := []struct {
+		cfg    Config
+		metric string
+	}{
+		{cfg: Config{PIDLabel: true}, metric: "rtmp_server_bytes_read_total"},
+		{cfg: Config{PIDLabel: true}, metric: "rtmp_server_bytes_sent_total"},
+		{cfg: Config{PIDLabel: true, UnifiedByteMetrics: true}, metric: "rtmp_bytes_total"},
+	}
+
+	for _, tc := range tt {
+		tc.cfg.StatsFiles = StringSlice{"testdata/stats.xml"}
+		mfs := gather(t, New(tc.cfg, log.NewNopLogger()))
+
+		for _, m := range findFamily(t, mfs, tc.metric).Metric {
+			require.Equal(t, "13", labelValue(m, "pid"), tc.metric)
+		}
+	}
+
+	mfs := gather(t, New(Config{StatsFiles: StringSlice{"testdata/stats.xml"}}, log.NewNopLogger()))
+	mf := findFamily(t, mfs, "rtmp_server_bytes_read_total")
+	require.Empty(t, mf.Metric[0].Label)
+}
+
+func TestExporter_InvalidMetrics(t *testing.T) {
+	addInvalidStream := func(s *rtmpstats.Stats) error {
+		s.Applications[0].Streams = append(s.Applications[0].Streams, rtmpstats.Stream{Name: "invalid\xff"})
+		return nil
+	}
+	mfs := gather(t, New(Config{StatsFiles: StringSlice{"testdata/stats.xml"}}, log.NewNopLogger(), addInvalidStream))
+
+	mf := findFamily(t, mfs, "rtmp_stream_uptime_seconds")
+	require.Len(t, mf.Metric, 1)
+	require.Equal(t, "streamName", labelValue(mf.Metric[0], "stream"))
+
+	mf = findFamily(t, mfs, "rtmp_stream_client_uptime_seconds")
+	require.Len(t, mf.Metric, 1)
+
+	findFamily(t, mfs, "rtmp_total_viewers")
+}
+
+func TestExporter_DropZeroSeries(t *testing.T) {
+	addIdleStream := func(s *rtmpstats.Stats) error {
+		s.Applications[0].Streams = append(s.Applications[0].Streams, rtmpstats.Stream{Name: "idle"})
+		return nil
+	}
+
+	streams := func(mf *dto.MetricFamily) map[string]float64 {
+		res := make(map[string]float64)
+		for _, m := range mf.Metric {
+			res[labelValue(m, "stream")] = m.GetGauge().GetValue()
+		}
+		return res
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		mfs := gather(t, New(Config{StatsFiles: StringSlice{"testdata/stats.xml"}}, log.NewNopLogger(), addIdleStream))
+
+		require.Equal(t, map[string]float64{"streamName": 1, "idle": 0}, streams(findFamily(t, mfs, "rtmp_stream_active")))
+		require.Equal(t, map[string]float64{"streamName": 2333128, "idle": 0}, streams(findFamily(t, mfs, "rtmp_stream_bitrate_in")))
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		cfg := Config{StatsFiles: StringSlice{"testdata/stats.xml"}, DropZeroSeries: true}
+		mfs := gather(t, New(cfg, log.NewNopLogger(), addIdleStream))
+
+		require.Equal(t, map[string]float64{"streamName": 1, "idle": 0}, streams(findFamily(t, mfs, "rtmp_stream_active")))
+		require.Equal(t, map[string]float64{"streamName": 2333128}, streams(findFamily(t, mfs, "rtmp_stream_bitrate_in")))
+		require.Equal(t, map[string]float64{"streamName": 4}, streams(findFamily(t, mfs, "rtmp_stream_current_clients")))
+		require.Equal(t, map[string]float64{"streamName": 0}, streams(findFamily(t, mfs, "rtmp_stream_client_count_mismatch")))
+
+		apps := findFamily(t, mfs, "rtmp_application_total_streams")
+		require.Equal(t, 2.0, apps.Metric[0].GetGauge().GetValue())
+	})
+}
+
+func TestExporter_ScrapesTotal(t *testing.T) {
+	e := New(Config{StatsFiles: StringSlice{"testdata/missing.xml"}}, log.NewNopLogger())
+
+	reg := prometheus.NewRegistry()
+	require.NoError(t, reg.Register(e))
+
+	for i := 1; i <= 2; i++ {
+		mfs, err := reg.Gather()
+		require.NoError(t, err)
+
+		scrapes := findFamily(t, mfs, "rtmp_scrapes_total")
+		require.Equal(t, float64(i), scrapes.Metric[0].GetCounter().GetValue())
+
+		errors := findFamily(t, mfs, "rtmp_scrape_errors_total")
+		require.Equal(t, float64(i), errors.Metric[0].GetCounter().GetValue())
+	}
+}
+
+func TestExporter_DecodeWarnings(t *testing.T) {
+	e := New(Config{StatsFiles: StringSlice{"testdata/stats_malformed.xml"}}, log.NewNopLogger())
+
+	reg := prometheus.NewRegistry()
+	require.NoError(t, reg.Register(e))
+
+	for i := 1; i <= 2; i++ {
+		mfs, err := reg.Gather()
+		require.NoError(t, err)
+
+		warnings := make(map[string]float64)
+		for _, m := range findFamily(t, mfs, "rtmp_stats_decode_warnings_total").Metric {
+			warnings[labelValue(m, "element")] = m.GetCounter().GetValue()
+		}
+		n := float64(i)
+		require.Equal(t, map[string]float64{"time": n, "stream": n, "duration": n, "client": n, "boolean": n}, warnings)
+
+		mf := findFamily(t, mfs, "rtmp_stream_uptime_seconds")
+		require.Len(t, mf.Metric, 1)
+	}
+}
+
+func TestExporter_IgnoreMutatorErrors(t *testing.T) {
+	failing := func(s *rtmpstats.Stats) error {
+		return errors.New("mutator failed")
+	}
+	rename := func(s *rtmpstats.Stats) error {
+		s.Applications[0].Name = "renamed"
+		return nil
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		mfs := gather(t, New(Config{StatsFiles: StringSlice{"testdata/stats.xml"}}, log.NewNopLogger(), failing, rename))
+
+		errors := findFamily(t, mfs, "rtmp_scrape_errors_total")
+		require.Equal(t, 1.0, errors.Metric[0].GetCounter().GetValue())
+		for _, mf := range mfs {
+			require.NotEqual(t, "rtmp_stream_uptime_seconds", mf.GetName())
+		}
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		cfg := Config{StatsFiles: StringSlice{"testdata/stats.xml"}, IgnoreMutatorErrors: true}
+		mfs := gather(t, New(cfg, log.NewNopLogger(), failing, rename))
+
+		errors := findFamily(t, mfs, "rtmp_scrape_errors_total")
+		require.Equal(t, 0.0, errors.Metric[0].GetCounter().GetValue())
+
+		mutatorErrors := findFamily(t, mfs, "rtmp_mutator_errors_total")
+		require.Equal(t, 1.0, mutatorErrors.Metric[0].GetCounter().GetValue())
+
+		degraded := findFamily(t, mfs, "rtmp_degraded")
+		require.Equal(t, 1.0, degraded.Metric[0].GetGauge().GetValue())
+
+		uptime := findFamily(t, mfs, "rtmp_stream_uptime_seconds")
+		require.Equal(t, "renamed", labelValue(uptime.Metric[0], "application"))
+	})
+}
+
+func TestExporter_Health(t *testing.T) {
+	failing := func(s *rtmpstats.Stats) error {
+		return errors.New("mutator failed")
+	}
+
+	var expectLive, expectMissing Patterns
+	require.NoError(t, expectLive.Set("live/.*"))
+	require.NoError(t, expectMissing.Set("live/missing"))
+
+	tt := []struct {
+		name     string
+		cfg      Config
+		mutators []rtmpstats.Mutator
+		expect   float64
+	}{
+		{name: "healthy", cfg: Config{StatsFiles: StringSlice{"testdata/stats.xml"}}, expect: 1},
+		{name: "scrape failed", cfg: Config{StatsFiles: StringSlice{"testdata/missing.xml"}}, expect: 0},
+		{name: "no streams", cfg: Config{StatsFiles: StringSlice{"testdata/stats_apps.xml"}, ApplicationAllowlist: expectMissing}, expect: 0},
+		{name: "expected stream", cfg: Config{StatsFiles: StringSlice{"testdata/stats.xml"}, HealthExpectedStreams: expectLive}, expect: 1},
+		{name: "missing expected stream", cfg: Config{StatsFiles: StringSlice{"testdata/stats.xml"}, HealthExpectedStreams: expectMissing}, expect: 0},
+		{
+			name:     "mutator error",
+			cfg:      Config{StatsFiles: StringSlice{"testdata/stats.xml"}, IgnoreMutatorErrors: true},
+			mutators: []rtmpstats.Mutator{failing},
+			expect:   0,
+		},
+		{
+			name:     "ignored mutator error",
+			cfg:      Config{StatsFiles: StringSlice{"testdata/stats.xml"}, IgnoreMutatorErrors: true, HealthIgnoreMutatorErrors: true},
+			mutators: []rtmpstats.Mutator{failing},
+			expect:   1,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			mfs := gather(t, New(tc.cfg, log.NewNopLogger(), tc.mutators...))
+			mf := findFamily(t, mfs, "rtmp_health")
+			require.Equal(t, tc.expect, mf.Metric[0].GetGauge().GetValue())
+		})
+	}
+}
+
+func TestExporter_Durations(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		http.ServeFile(w, r, "testdata/stats.xml")
+	}))
+	defer srv.Close()
+
+	cfg := Config{StatsURL: srv.URL, Timeout: time.Second}
+	mfs := gather(t, New(cfg, log.NewNopLogger()))
+
+	fetch := findFamily(t, mfs, "rtmp_fetch_duration_seconds").Metric[0].GetGauge().GetValue()
+	require.GreaterOrEqual(t, fetch, 0.05)
+
+	parse := findFamily(t, mfs, "rtmp_parse_duration_seconds").Metric[0].GetGauge().GetValue()
+	require.Greater(t, parse, float64(0))
+	require.Less(t, parse, fetch)
+}
+
+func TestExporter_Up(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mfs := gather(t, New(Config{StatsFiles: StringSlice{"testdata/stats.xml"}}, log.NewNopLogger()))
+
+		up := findFamily(t, mfs, "rtmp_up")
+		require.Equal(t, 1.0, up.Metric[0].GetGauge().GetValue())
+		duration := findFamily(t, mfs, "rtmp_scrape_duration_seconds")
+		require.Greater(t, duration.Metric[0].GetGauge().GetValue(), float64(0))
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		mfs := gather(t, New(Config{StatsFiles: StringSlice{"testdata/missing.xml"}}, log.NewNopLogger()))
+
+		up := findFamily(t, mfs, "rtmp_up")
+		require.Equal(t, 0.0, up.Metric[0].GetGauge().GetValue())
+		findFamily(t, mfs, "rtmp_scrape_duration_seconds")
+	})
+}
+
+func TestExporter_MultipleURLs(t *testing.T) {
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/stats.xml")
+	}))
+	defer good.Close()
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "oops", http.StatusInternalServerError)
+	}))
+	defer bad.Close()
+
+	cfg := Config{
+		StatsURLs:      Endpoints{{Name: "edge-1", URL: good.URL}, {Name: "edge-2", URL: bad.URL}},
+		Timeout:        time.Second,
+		ConstantLabels: Labels{"region": "us-east"},
+	}
+	require.NoError(t, cfg.Validate())
+	e := New(cfg, log.NewNopLogger())
+	mfs := gather(t, e)
+
+	up := make(map[string]float64)
+	for _, m := range findFamily(t, mfs, "rtmp_up").Metric {
+		require.Equal(t, "us-east", labelValue(m, "region"))
+		up[labelValue(m, "instance")] = m.GetGauge().GetValue()
+	}
+	require.Equal(t, map[string]float64{"edge-1": 1, "edge-2": 0}, up)
+
+	mf := findFamily(t, mfs, "rtmp_stream_uptime_seconds")
+	require.Len(t, mf.Metric, 1)
+	require.Equal(t, "edge-1", labelValue(mf.Metric[0], "instance"))
+
+	// Exporter metrics are exposed once, without an instance label.
+	for _, name := range []string{"rtmp_exporter_start_time_seconds", "rtmp_scrapes_total", "rtmp_scrape_errors_total", "rtmp_exporter_active_connections"} {
+		mf := findFamily(t, mfs, name)
+		require.Len(t, mf.Metric, 1, name)
+		require.Equal(t, "", labelValue(mf.Metric[0], "instance"), name)
+		require.Equal(t, "us-east", labelValue(mf.Metric[0], "region"), name)
+	}
+	require.Equal(t, float64(1), findFamily(t, mfs, "rtmp_scrape_errors_total").Metric[0].GetCounter().GetValue())
+
+	rec := httptest.NewRecorder()
+	e.ReadyHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/-/ready", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	// Stats leaves out failing endpoints and only fails when all of them do.
+	s, err := e.Stats(context.Background())
+	require.NoError(t, err)
+	require.Len(t, s.Applications, 1)
+	require.Equal(t, "streamName", s.Applications[0].Streams[0].Name)
+
+	failing := New(Config{StatsURLs: Endpoints{{Name: "edge-2", URL: bad.URL}, {Name: "edge-3", URL: bad.URL}}, Timeout: time.Second}, log.NewNopLogger())
+	_, err = failing.Stats(context.Background())
+	require.Error(t, err)
+
+	cfg.StatsURLs[1].Name = "edge-1"
+	require.Error(t, cfg.Validate())
+	cfg.StatsURLs[1].Name = "edge-2"
+	cfg.ConstantLabels = Labels{"instance": "rtmp"}
+	require.Error(t, cfg.Validate())
+}
+
+func TestExporter_ResponseBytes(t *testing.T) {
+	fi, err := os.Stat("testdata/stats.xml")
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/stats.xml")
+	}))
+	defer srv.Close()
+
+	mfs := gather(t, New(Config{StatsURL: srv.URL, Timeout: time.Second}, log.NewNopLogger()))
+	mf := findFamily(t, mfs, "rtmp_stats_response_bytes")
+	require.Equal(t, float64(fi.Size()), mf.Metric[0].GetGauge().GetValue())
+}
+
+// gather registers e against a new registry and returns the gathered metric
+// families.
+func gather(t *testing.T, e *Exporter) []*dto.MetricFamily {
+	t.Helper()
+
+	reg := prometheus.NewRegistry()
+	require.NoError(t, reg.Register(e))
+
+	mfs, err := reg.Gather()
+	require.NoError(t, err)
+	return mfs
+}
+
+// findFamily returns the metric family with the given name from mfs.
+func findFamily(t *testing.T, mfs []*dto.MetricFamily, name string) *dto.MetricFamily {
+	t.Helper()
+
+	for _, mf := range mfs {
+		if mf.GetName() == name {
+			return mf
+		}
+	}
+	require.FailNow(t, "metric family not found", name)
+	return nil
+}
+
+// labelValue returns the value of the label with the given name from m.
+func labelValue(m *dto.Metric, name string) string {
+	for _, l := range m.Label {
+		if l.GetName() == name {
+			return l.GetValue()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,42 @@
+//go:build !windows
+// +build !windows
+
+package exporter
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExporter_FileTimeout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rtmp_exporter")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// Opening a FIFO for reading blocks until a writer opens it, standing in
+	// for a hung network file system.
+	path := filepath.Join(dir, "stats.xml")
+	require.NoError(t, syscall.Mkfifo(path, 0600))
+	defer func() {
+		// Unblock the abandoned read.
+		if f, err := os.OpenFile(path, os.O_WRONLY, 0); err == nil {
+			f.Close()
+		}
+	}()
+
+	e := New(Config{StatsFiles: StringSlice{path}, FileTimeout: 50 * time.Millisecond, Timeout: time.Hour}, log.NewNopLogger())
+
+	start := time.Now()
+	_, err = e.Stats(context.Background())
+	require.True(t, errors.Is(err, context.DeadlineExceeded), "unexpected error %v", err)
+	require.Less(t, int64(time.Since(start)), int64(5*time.Second))
+}
@@ -0,0 +1,285 @@
+package exporter
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Buckets is a list of histogram buckets that can be set from a
+// comma-separated flag.
+type Buckets []float64
+
+// String implements flag.Value.
+func (b *Buckets) String() string {
+	strs := make([]string, 0, len(*b))
+	for _, v := range *b {
+		strs = append(strs, strconv.FormatFloat(v, 'g', -1, 64))
+	}
+	return strings.Join(strs, ",")
+}
+
+// Set implements flag.Value.
+func (b *Buckets) Set(in string) error {
+	var res Buckets
+	for _, str := range strings.Split(in, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(str), 64)
+		if err != nil {
+			return fmt.Errorf("invalid bucket %q: %w", str, err)
+		}
+		if len(res) > 0 && v <= res[len(res)-1] {
+			return fmt.Errorf("buckets must be in increasing order")
+		}
+		res = append(res, v)
+	}
+	*b = res
+	return nil
+}
+
+// Strings is a list of strings that can be set from a comma-separated flag.
+type Strings []string
+
+// String implements flag.Value.
+func (s *Strings) String() string { return strings.Join(*s, ",") }
+
+// Set implements flag.Value.
+func (s *Strings) Set(in string) error {
+	var res Strings
+	for _, str := range strings.Split(in, ",") {
+		if str = strings.TrimSpace(str); str != "" {
+			res = append(res, str)
+		}
+	}
+	*s = res
+	return nil
+}
+
+// StringSlice is a list of strings that can be set by repeating a flag.
+type StringSlice []string
+
+// String implements flag.Value.
+func (s *StringSlice) String() string { return strings.Join(*s, ",") }
+
+// Set implements flag.Value. Set appends to the list rather than replacing
+// it.
+func (s *StringSlice) Set(in string) error {
+	*s = append(*s, in)
+	return nil
+}
+
+// Endpoint is a stats URL identified by a name.
+type Endpoint struct {
+	Name string
+	URL  string
+}
+
+// Endpoints is a list of endpoints that can be set by repeating a flag. Each
+// value is a URL optionally prefixed by a name, as in name=url. The name
+// defaults to the host of the URL.
+type Endpoints []Endpoint
+
+// String implements flag.Value.
+func (e *Endpoints) String() string {
+	strs := make([]string, 0, len(*e))
+	for _, ep := range *e {
+		strs = append(strs, ep.Name+"="+ep.URL)
+	}
+	return strings.Join(strs, ",")
+}
+
+// Set implements flag.Value. Set appends to the list rather than replacing
+// it.
+func (e *Endpoints) Set(in string) error {
+	ep := Endpoint{URL: in}
+
+	// Anything before the first = is a name unless it's part of the URL,
+	// such as in a query string.
+	if i := strings.Index(in, "="); i > 0 && !strings.ContainsAny(in[:i], ":/?") {
+		ep.Name, ep.URL = in[:i], in[i+1:]
+	}
+
+	u, err := url.Parse(ep.URL)
+	if err != nil {
+		return fmt.Errorf("invalid stats URL %q: %w", ep.URL, err)
+	}
+	if ep.Name == "" {
+		ep.Name = u.Host
+	}
+	if ep.Name == "" {
+		return fmt.Errorf("stats URL %q must have a host or be in the form name=url", in)
+	}
+
+	*e = append(*e, ep)
+	return nil
+}
+
+// Patterns is a list of regular expressions that can be set by repeating a
+// flag. Each pattern is anchored so that it must match an entire string.
+type Patterns []*regexp.Regexp
+
+// String implements flag.Value.
+func (p *Patterns) String() string {
+	strs := make([]string, 0, len(*p))
+	for _, re := range *p {
+		strs = append(strs, strings.TrimSuffix(strings.TrimPrefix(re.String(), "^(?:"), ")$"))
+	}
+	return strings.Join(strs, ",")
+}
+
+// Set implements flag.Value. Set appends to the list of patterns rather than
+// replacing it.
+func (p *Patterns) Set(in string) error {
+	re, err := regexp.Compile("^(?:" + in + ")$")
+	if err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", in, err)
+	}
+	*p = append(*p, re)
+	return nil
+}
+
+// Empty returns true when there are no patterns.
+func (p Patterns) Empty() bool { return len(p) == 0 }
+
+// Match returns true if any pattern matches s.
+func (p Patterns) Match(s string) bool {
+	for _, re := range p {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// HostOverride pins a hostname to a specific IP address, bypassing DNS. It is
+// set from a flag in the form host:ip.
+type HostOverride struct {
+	Host string
+	IP   string
+}
+
+// String implements flag.Value.
+func (o *HostOverride) String() string {
+	if o.Host == "" {
+		return ""
+	}
+	return o.Host + ":" + o.IP
+}
+
+// Set implements flag.Value.
+func (o *HostOverride) Set(in string) error {
+	parts := strings.SplitN(in, ":", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("host override %q must be in the form host:ip", in)
+	}
+	if net.ParseIP(parts[1]) == nil {
+		return fmt.Errorf("invalid IP address %q in host override", parts[1])
+	}
+
+	o.Host, o.IP = parts[0], parts[1]
+	return nil
+}
+
+// Rewrite replaces the host of addr (in the form host:port) with the
+// overridden IP address if it matches the overridden host. Otherwise, addr is
+// returned unchanged.
+func (o HostOverride) Rewrite(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || o.Host == "" || !strings.EqualFold(host, o.Host) {
+		return addr
+	}
+	return net.JoinHostPort(o.IP, port)
+}
+
+// LevelBitrates maps H.264 levels (e.g., 4.1) to the maximum video bitrate in
+// kbit/s allowed at that level. It can be set from a comma-separated flag of
+// level=kbps pairs, which are merged into the existing entries.
+type LevelBitrates map[float64]int
+
+// String implements flag.Value.
+func (l *LevelBitrates) String() string {
+	levels := make([]float64, 0, len(*l))
+	for level := range *l {
+		levels = append(levels, level)
+	}
+	sort.Float64s(levels)
+
+	strs := make([]string, 0, len(levels))
+	for _, level := range levels {
+		strs = append(strs, fmt.Sprintf("%s=%d", strconv.FormatFloat(level, 'g', -1, 64), (*l)[level]))
+	}
+	return strings.Join(strs, ",")
+}
+
+// Set implements flag.Value.
+func (l *LevelBitrates) Set(in string) error {
+	res := make(LevelBitrates, len(*l))
+	for level, kbps := range *l {
+		res[level] = kbps
+	}
+
+	for _, pair := range strings.Split(in, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("level bitrate %q must be in the form level=kbps", pair)
+		}
+		level, err := strconv.ParseFloat(parts[0], 64)
+		if err != nil {
+			return fmt.Errorf("invalid level %q: %w", parts[0], err)
+		}
+		kbps, err := strconv.Atoi(parts[1])
+		if err != nil || kbps <= 0 {
+			return fmt.Errorf("invalid bitrate %q for level %s", parts[1], parts[0])
+		}
+		res[level] = kbps
+	}
+	*l = res
+	return nil
+}
+
+// labelNameRegexp matches valid Prometheus label names.
+var labelNameRegexp = regexp.MustCompile("^[a-zA-Z_][a-zA-Z0-9_]*$")
+
+// Labels is a set of Prometheus labels that can be set from a comma-separated
+// flag of name=value pairs.
+type Labels prometheus.Labels
+
+// String implements flag.Value.
+func (l *Labels) String() string {
+	strs := make([]string, 0, len(*l))
+	for name, value := range *l {
+		strs = append(strs, name+"="+value)
+	}
+	sort.Strings(strs)
+	return strings.Join(strs, ",")
+}
+
+// Set implements flag.Value.
+func (l *Labels) Set(in string) error {
+	res := make(Labels)
+	for _, pair := range strings.Split(in, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("label %q must be in the form name=value", pair)
+		}
+		if !labelNameRegexp.MatchString(parts[0]) {
+			return fmt.Errorf("invalid label name %q", parts[0])
+		}
+		res[parts[0]] = parts[1]
+	}
+	*l = res
+	return nil
+}
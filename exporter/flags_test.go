@@ -0,0 +1,111 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuckets_Set(t *testing.T) {
+	var b Buckets
+	require.NoError(t, b.Set("1, 2.5,10"))
+	require.Equal(t, Buckets{1, 2.5, 10}, b)
+	require.Equal(t, "1,2.5,10", b.String())
+
+	require.Error(t, b.Set("10,1"))
+	require.Error(t, b.Set("a"))
+}
+
+func TestLevelBitrates_Set(t *testing.T) {
+	l := LevelBitrates{3: 10000, 4: 20000}
+	require.NoError(t, l.Set("4=25000, 5.1=240000"))
+	require.Equal(t, LevelBitrates{3: 10000, 4: 25000, 5.1: 240000}, l)
+	require.Equal(t, "3=10000,4=25000,5.1=240000", l.String())
+
+	require.Error(t, l.Set("4"))
+	require.Error(t, l.Set("four=25000"))
+	require.Error(t, l.Set("4=-1"))
+}
+
+func TestStrings_Set(t *testing.T) {
+	var s Strings
+	require.NoError(t, s.Set("a, b,,c"))
+	require.Equal(t, Strings{"a", "b", "c"}, s)
+	require.Equal(t, "a,b,c", s.String())
+
+	require.NoError(t, s.Set(""))
+	require.Empty(t, s)
+}
+
+func TestStringSlice_Set(t *testing.T) {
+	var s StringSlice
+	require.NoError(t, s.Set("a.xml"))
+	require.NoError(t, s.Set("b,c.xml"))
+	require.Equal(t, StringSlice{"a.xml", "b,c.xml"}, s)
+}
+
+func TestEndpoints_Set(t *testing.T) {
+	var e Endpoints
+	require.NoError(t, e.Set("http://edge-1:8080/stat"))
+	require.NoError(t, e.Set("edge-2=http://10.0.0.2/stat?format=xml"))
+	require.NoError(t, e.Set("http://edge-3/stat?format=xml"))
+	require.Equal(t, Endpoints{
+		{Name: "edge-1:8080", URL: "http://edge-1:8080/stat"},
+		{Name: "edge-2", URL: "http://10.0.0.2/stat?format=xml"},
+		{Name: "edge-3", URL: "http://edge-3/stat?format=xml"},
+	}, e)
+
+	require.Error(t, e.Set("/stat"))
+	require.Error(t, e.Set("edge=http://%zz"))
+}
+
+func TestPatterns(t *testing.T) {
+	var p Patterns
+	require.True(t, p.Empty())
+
+	require.NoError(t, p.Set("live"))
+	require.NoError(t, p.Set("tenant-.*"))
+	require.Equal(t, "live,tenant-.*", p.String())
+
+	tt := map[string]bool{
+		"live":         true,
+		"live2":        false,
+		"relive":       false,
+		"tenant-a":     true,
+		"tenant-":      true,
+		"othertenant-": false,
+	}
+	for name, expect := range tt {
+		require.Equal(t, expect, p.Match(name), name)
+	}
+
+	require.Error(t, p.Set("tenant-("))
+}
+
+func TestHostOverride(t *testing.T) {
+	var o HostOverride
+	require.Equal(t, "example.com:80", o.Rewrite("example.com:80"))
+
+	require.NoError(t, o.Set("example.com:10.0.0.1"))
+	require.Equal(t, "example.com:10.0.0.1", o.String())
+	require.Equal(t, "10.0.0.1:80", o.Rewrite("example.com:80"))
+	require.Equal(t, "10.0.0.1:443", o.Rewrite("EXAMPLE.com:443"))
+	require.Equal(t, "other.com:80", o.Rewrite("other.com:80"))
+
+	require.NoError(t, o.Set("example.com:::1"))
+	require.Equal(t, "[::1]:80", o.Rewrite("example.com:80"))
+
+	require.Error(t, o.Set("example.com"))
+	require.Error(t, o.Set(":10.0.0.1"))
+	require.Error(t, o.Set("example.com:not-an-ip"))
+}
+
+func TestLabels_Set(t *testing.T) {
+	var l Labels
+	require.NoError(t, l.Set("region=us-east, env=prod"))
+	require.Equal(t, Labels{"region": "us-east", "env": "prod"}, l)
+	require.Equal(t, "env=prod,region=us-east", l.String())
+
+	require.Error(t, l.Set("region"))
+	require.Error(t, l.Set("1region=us-east"))
+}
@@ -0,0 +1,64 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/graphite"
+)
+
+// GraphiteBridge returns a bridge that pushes the metrics of the Exporter to
+// the configured GraphiteAddress. Metric names and labels are translated to
+// dotted Graphite paths. The caller is expected to start the bridge with Run.
+//
+// Every push retrieves the stats through a separate Exporter, so pushes
+// aren't counted in rtmp_scrapes_total and don't advance the state kept
+// between Prometheus scrapes. Pushes keep their own state instead: metrics
+// like smoothed bitrates and client churn are computed across pushes at
+// GraphiteInterval. ChangeLog only logs changes seen by Prometheus scrapes.
+func (e *Exporter) GraphiteBridge() (*graphite.Bridge, error) {
+	pushCfg := e.cfg
+	pushCfg.ChangeLog = false
+	push := NewWithNamedMutators(pushCfg, e.logger, e.mutators)
+
+	reg := prometheus.NewRegistry()
+	if err := reg.Register(graphiteCollector{e: e, push: push}); err != nil {
+		return nil, fmt.Errorf("registering exporter: %w", err)
+	}
+
+	return graphite.NewBridge(&graphite.Config{
+		URL:           e.cfg.GraphiteAddress,
+		Prefix:        e.cfg.GraphitePrefix,
+		Interval:      e.cfg.GraphiteInterval,
+		Timeout:       e.cfg.Timeout,
+		Gatherer:      reg,
+		Logger:        graphiteLogger{e},
+		ErrorHandling: graphite.ContinueOnError,
+	})
+}
+
+// graphiteCollector is a prometheus.Collector that delivers the metrics
+// derived from the stats retrieved by push along with the exporter metrics of
+// e.
+type graphiteCollector struct {
+	e, push *Exporter
+}
+
+// Describe implements prometheus.Collector.
+func (c graphiteCollector) Describe(ch chan<- *prometheus.Desc) { c.e.Describe(ch) }
+
+// Collect implements prometheus.Collector.
+func (c graphiteCollector) Collect(ch chan<- prometheus.Metric) {
+	c.push.collectSources(context.Background(), ch)
+	c.e.collectExporterMetrics(ch)
+}
+
+// graphiteLogger adapts the Exporter's logger to the graphite.Logger
+// interface.
+type graphiteLogger struct{ e *Exporter }
+
+func (l graphiteLogger) Println(v ...interface{}) {
+	level.Warn(l.e.logger).Log("msg", "graphite bridge", "err", fmt.Sprint(v...))
+}
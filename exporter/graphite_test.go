@@ -0,0 +1,64 @@
+package exporter
+
+import (
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraphiteBridge(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf, _ := ioutil.ReadAll(conn)
+		received <- string(buf)
+	}()
+
+	cfg := Config{
+		StatsFiles:      StringSlice{"testdata/stats.xml"},
+		GraphiteAddress: lis.Addr().String(),
+		GraphitePrefix:  "rtmp",
+		BitrateWindow:   5,
+	}
+	require.NoError(t, cfg.Validate())
+
+	e := New(cfg, log.NewNopLogger())
+	bridge, err := e.GraphiteBridge()
+	require.NoError(t, err)
+	require.NoError(t, bridge.Push())
+
+	// Strip the timestamps, which are the last field of every line.
+	lines := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(<-received), "\n") {
+		lines[line[:strings.LastIndex(line, " ")]] = true
+	}
+
+	for _, expect := range []string{
+		"rtmp.rtmp_server_bitrate_in 2.338696e+06",
+		"rtmp.rtmp_server_bytes_read_total 1.30057972e+08",
+		"rtmp.rtmp_stream_bitrate_in.application.live.publisher.1.stream.streamName 2.333128e+06",
+		"rtmp.rtmp_scrapes_total 0",
+	} {
+		require.True(t, lines[expect], "missing line %q", expect)
+	}
+
+	// Pushes neither count as scrapes nor advance the state of the Exporter.
+	require.Empty(t, e.state.bitrateWindows)
+	mf := findFamily(t, gather(t, e), "rtmp_scrapes_total")
+	require.Equal(t, float64(1), mf.Metric[0].GetCounter().GetValue())
+
+	cfg.GraphiteAddress = "localhost"
+	require.Error(t, cfg.Validate())
+}
@@ -0,0 +1,71 @@
+package exporter
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// scrapeTimeoutOffset is subtracted from the scrape timeout sent by
+// Prometheus, leaving time to send the response before Prometheus gives up.
+const scrapeTimeoutOffset = 500 * time.Millisecond
+
+// MetricsHandler returns an http.Handler serving the metrics of e along with
+// the metrics gathered by g, which must not include e. When a request has an
+// X-Prometheus-Scrape-Timeout-Seconds header, retrieving the stats is bounded
+// to slightly less than the scrape timeout so the response is sent before
+// Prometheus gives up on the scrape.
+func (e *Exporter) MetricsHandler(g prometheus.Gatherer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if timeout, ok := scrapeTimeout(r); ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		reg := prometheus.NewRegistry()
+		if err := reg.Register(contextCollector{e: e, ctx: ctx}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		promhttp.HandlerFor(prometheus.Gatherers{g, reg}, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	})
+}
+
+// scrapeTimeout returns the time available to retrieve the stats for a scrape
+// from the X-Prometheus-Scrape-Timeout-Seconds header of r. ok is false when
+// the header is missing or invalid.
+func scrapeTimeout(r *http.Request) (timeout time.Duration, ok bool) {
+	header := r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds")
+	if header == "" {
+		return 0, false
+	}
+	seconds, err := strconv.ParseFloat(header, 64)
+	if err != nil || seconds <= 0 {
+		return 0, false
+	}
+
+	timeout = time.Duration(seconds * float64(time.Second))
+	if timeout > scrapeTimeoutOffset {
+		timeout -= scrapeTimeoutOffset
+	}
+	return timeout, true
+}
+
+// contextCollector is a prometheus.Collector that collects the metrics of an
+// Exporter using a context.
+type contextCollector struct {
+	e   *Exporter
+	ctx context.Context
+}
+
+// Describe implements prometheus.Collector.
+func (c contextCollector) Describe(ch chan<- *prometheus.Desc) { c.e.Describe(ch) }
+
+// Collect implements prometheus.Collector.
+func (c contextCollector) Collect(ch chan<- prometheus.Metric) { c.e.collect(c.ctx, ch) }
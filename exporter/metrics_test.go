@@ -0,0 +1,64 @@
+package exporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsHandler_ScrapeTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(5 * time.Second):
+		case <-r.Context().Done():
+		}
+		http.ServeFile(w, r, "testdata/stats.xml")
+	}))
+	defer srv.Close()
+
+	e := New(Config{StatsURL: srv.URL, Timeout: time.Minute}, log.NewNopLogger())
+	handler := e.MetricsHandler(prometheus.NewRegistry())
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", "1")
+	rec := httptest.NewRecorder()
+
+	start := time.Now()
+	handler.ServeHTTP(rec, req)
+	require.Less(t, int64(time.Since(start)), int64(5*time.Second))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var parser expfmt.TextParser
+	mfs, err := parser.TextToMetricFamilies(rec.Body)
+	require.NoError(t, err)
+	require.Equal(t, float64(1), mfs["rtmp_scrape_errors_total"].Metric[0].GetCounter().GetValue())
+}
+
+func TestScrapeTimeout(t *testing.T) {
+	tt := []struct {
+		header string
+		expect time.Duration
+		ok     bool
+	}{
+		{header: "", ok: false},
+		{header: "invalid", ok: false},
+		{header: "-1", ok: false},
+		{header: "10", expect: 9500 * time.Millisecond, ok: true},
+		{header: "0.25", expect: 250 * time.Millisecond, ok: true},
+	}
+
+	for _, tc := range tt {
+		req := httptest.NewRequest("GET", "/metrics", nil)
+		req.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", tc.header)
+
+		timeout, ok := scrapeTimeout(req)
+		require.Equal(t, tc.ok, ok, tc.header)
+		require.Equal(t, tc.expect, timeout, tc.header)
+	}
+}
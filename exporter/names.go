@@ -0,0 +1,99 @@
+package exporter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MetricNameData is the data available to Config.MetricNameTemplate.
+type MetricNameData struct {
+	// Application is the name of the application with characters that are
+	// invalid in metric names replaced by underscores.
+	Application string
+
+	// Name is the default name of the metric without the rtmp_ namespace,
+	// e.g., stream_bitrate_in.
+	Name string
+}
+
+var (
+	metricNameRegexp        = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	invalidMetricNameRegexp = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+)
+
+// descSpec holds the options used to create a per-application descriptor so
+// that it can be recreated under a different name.
+type descSpec struct {
+	name        string
+	help        string
+	labels      []string
+	constLabels prometheus.Labels
+}
+
+// parseMetricNameTemplate parses a metric name template and ensures that it
+// generates valid metric names.
+func parseMetricNameTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("metric_name").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid metric name template: %w", err)
+	}
+
+	_, err = executeMetricNameTemplate(tmpl, MetricNameData{Application: "live", Name: "stream_bitrate_in"})
+	if err != nil {
+		return nil, fmt.Errorf("invalid metric name template: %w", err)
+	}
+	return tmpl, nil
+}
+
+func executeMetricNameTemplate(tmpl *template.Template, data MetricNameData) (string, error) {
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", err
+	}
+
+	name := sb.String()
+	if !metricNameRegexp.MatchString(name) {
+		return "", fmt.Errorf("generated invalid metric name %q", name)
+	}
+	return name, nil
+}
+
+// applicationDesc returns the descriptor to use for d for metrics of the
+// given application. d is returned unchanged unless a metric name template is
+// configured. Generated descriptors are cached.
+func (e *Exporter) applicationDesc(app string, d *prometheus.Desc) *prometheus.Desc {
+	if e.nameTemplate == nil {
+		return d
+	}
+	spec, ok := e.appDescs[d]
+	if !ok {
+		return d
+	}
+
+	e.mut.Lock()
+	defer e.mut.Unlock()
+
+	key := app + "\xff" + spec.name
+	if desc, ok := e.appDescCache[key]; ok {
+		return desc
+	}
+
+	desc := d
+	name, err := executeMetricNameTemplate(e.nameTemplate, MetricNameData{
+		Application: invalidMetricNameRegexp.ReplaceAllString(app, "_"),
+		Name:        spec.name,
+	})
+	if err != nil {
+		level.Warn(e.logger).Log("msg", "failed to generate metric name, using default", "application", app, "metric", spec.name, "err", err)
+	} else {
+		desc = prometheus.NewDesc(name, spec.help, spec.labels, spec.constLabels)
+	}
+
+	e.appDescCache[key] = desc
+	return desc
+}
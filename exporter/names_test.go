@@ -0,0 +1,57 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExporter_MetricNameTemplate(t *testing.T) {
+	cfg := Config{
+		StatsFiles:         StringSlice{"testdata/stats_apps.xml"},
+		MetricNameTemplate: "rtmp_{{.Application}}_{{.Name}}",
+	}
+	require.NoError(t, cfg.Validate())
+
+	mfs := gather(t, New(cfg, log.NewNopLogger()))
+
+	var names []string
+	for _, mf := range mfs {
+		if strings.HasSuffix(mf.GetName(), "_stream_bitrate_in") {
+			names = append(names, mf.GetName())
+		}
+	}
+	require.ElementsMatch(t, []string{
+		"rtmp_live_stream_bitrate_in",
+		"rtmp_tenant_a_stream_bitrate_in",
+		"rtmp_tenant_b_stream_bitrate_in",
+	}, names)
+
+	// Metrics without an application label keep their default name.
+	findFamily(t, mfs, "rtmp_server_bitrate_in")
+	findFamily(t, mfs, "rtmp_playback_application_clients")
+}
+
+func TestParseMetricNameTemplate(t *testing.T) {
+	tt := []struct {
+		text      string
+		expectErr bool
+	}{
+		{text: "rtmp_{{.Application}}_{{.Name}}"},
+		{text: "{{.Name}}_{{.Application}}"},
+		{text: "rtmp_{{.Application", expectErr: true},
+		{text: "rtmp_{{.Missing}}", expectErr: true},
+		{text: "rtmp-{{.Application}}", expectErr: true},
+	}
+
+	for _, tc := range tt {
+		_, err := parseMetricNameTemplate(tc.text)
+		if tc.expectErr {
+			require.Error(t, err, tc.text)
+		} else {
+			require.NoError(t, err, tc.text)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package exporter
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ReadyHandler returns an http.Handler that reports whether the last scrape
+// succeeded. The handler always reports ready during the configured
+// StartupGracePeriod so that probes don't fail while nginx is starting.
+func (e *Exporter) ReadyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if time.Since(e.started) < e.cfg.StartupGracePeriod {
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintln(w, "ready (startup grace period)")
+			return
+		}
+
+		e.mut.Lock()
+		ready := e.lastScrapeSucceeded
+		e.mut.Unlock()
+
+		if !ready {
+			http.Error(w, "not ready: last scrape failed", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintln(w, "ready")
+	})
+}
+
+func (e *Exporter) setLastScrapeSucceeded(succeeded bool) {
+	e.mut.Lock()
+	defer e.mut.Unlock()
+	e.lastScrapeSucceeded = succeeded
+}
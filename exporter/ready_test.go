@@ -0,0 +1,42 @@
+package exporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadyHandler(t *testing.T) {
+	ready := func(e *Exporter) int {
+		rec := httptest.NewRecorder()
+		e.ReadyHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/-/ready", nil))
+		return rec.Code
+	}
+
+	t.Run("grace period", func(t *testing.T) {
+		cfg := Config{StatsFiles: StringSlice{"testdata/missing.xml"}, StartupGracePeriod: time.Hour}
+		e := New(cfg, log.NewNopLogger())
+		require.Equal(t, http.StatusOK, ready(e))
+
+		gather(t, e)
+		require.Equal(t, http.StatusOK, ready(e))
+	})
+
+	t.Run("after grace period", func(t *testing.T) {
+		cfg := Config{StatsFiles: StringSlice{"testdata/missing.xml"}, StartupGracePeriod: time.Minute}
+		e := New(cfg, log.NewNopLogger())
+		e.started = time.Now().Add(-2 * time.Minute)
+		require.Equal(t, http.StatusServiceUnavailable, ready(e))
+
+		gather(t, e)
+		require.Equal(t, http.StatusServiceUnavailable, ready(e))
+
+		e.cfg.StatsFiles = StringSlice{"testdata/stats.xml"}
+		gather(t, e)
+		require.Equal(t, http.StatusOK, ready(e))
+	})
+}
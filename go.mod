@@ -3,12 +3,16 @@ module github.com/rfratto/rtmp_exporter
 go 1.14
 
 require (
-	github.com/cortexproject/cortex v1.0.1
 	github.com/go-kit/kit v0.10.0
+	github.com/golang/protobuf v1.5.3
 	github.com/google/go-cmp v0.5.1 // indirect
 	github.com/prometheus/client_golang v1.5.0
 	github.com/stretchr/testify v1.4.0
 	github.com/weaveworks/common v0.0.0-20200310113808-2708ba4e60a4
 	golang.org/x/net v0.0.0-20200602114024-627f9648deb9
+	golang.org/x/oauth2 v0.0.0-20190604053449-0f29369cfe45
+	google.golang.org/grpc v1.59.0
+	google.golang.org/protobuf v1.31.0
+	gopkg.in/yaml.v2 v2.2.7
 	gotest.tools v2.2.0+incompatible
 )
@@ -0,0 +1,248 @@
+package rtmpstats
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Decoder reads streams one at a time from a stats document, allowing large
+// documents to be processed without holding every stream in memory.
+type Decoder struct {
+	d *xml.Decoder
+
+	// Path of element names from the document root to the current token.
+	path []string
+
+	// Tokens of the root element outside of <server>, decoded into stats once
+	// the root element is closed.
+	header []xml.Token
+
+	stats    Stats
+	finished bool
+}
+
+// NewDecoder creates a new Decoder reading from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{d: xml.NewDecoder(r)}
+}
+
+// Next returns the next stream in the document. Application returns the name
+// of the application the stream belongs to. Next returns io.EOF once there are
+// no more streams.
+func (d *Decoder) Next() (*Stream, error) {
+	if d.finished {
+		return nil, io.EOF
+	}
+
+	for {
+		tok, err := d.d.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			d.path = append(d.path, t.Name.Local)
+
+			switch d.location() {
+			case "server/application":
+				// The name may also be set by a <name> element, which takes
+				// precedence.
+				var app Application
+				for _, attr := range t.Attr {
+					if attr.Name.Local == "name" {
+						app.Name = attr.Value
+					}
+				}
+				d.stats.Applications = append(d.stats.Applications, app)
+			case "server/application/name":
+				var name string
+				if err := d.d.DecodeElement(&name, &t); err != nil {
+					return nil, err
+				}
+				d.path = d.path[:len(d.path)-1]
+				if name != "" {
+					d.stats.Applications[len(d.stats.Applications)-1].Name = name
+				}
+			case "server/application/live/stream":
+				var s Stream
+				err := d.d.DecodeElement(&s, &t)
+				d.path = d.path[:len(d.path)-1]
+
+				var warn *decodeWarning
+				if errors.As(err, &warn) {
+					d.stats.DecodeWarnings.add(warn.element, 1)
+					continue
+				} else if err != nil {
+					return nil, err
+				}
+				d.stats.DecodeWarnings.merge(s.decodeWarnings)
+				return &s, nil
+			default:
+				d.appendHeader(t.Copy())
+			}
+
+		case xml.EndElement:
+			d.appendHeader(t)
+			d.path = d.path[:len(d.path)-1]
+
+			if len(d.path) == 0 {
+				if err := d.decodeHeader(); err != nil {
+					return nil, err
+				}
+				d.finished = true
+				return nil, io.EOF
+			}
+
+		default:
+			d.appendHeader(xml.CopyToken(tok))
+		}
+	}
+}
+
+// Application returns the name of the application of the stream most
+// recently returned by Next.
+func (d *Decoder) Application() string {
+	if len(d.stats.Applications) == 0 {
+		return ""
+	}
+	return d.stats.Applications[len(d.stats.Applications)-1].Name
+}
+
+// Stats returns the applications seen so far, without their streams. The
+// server-level fields are only set once Next has returned io.EOF.
+func (d *Decoder) Stats() *Stats {
+	return &d.stats
+}
+
+// location returns the path of the current element relative to the document
+// root.
+func (d *Decoder) location() string {
+	if len(d.path) <= 1 {
+		return ""
+	}
+	return strings.Join(d.path[1:], "/")
+}
+
+// appendHeader retains tok if it's part of the root element but not within
+// <server>.
+func (d *Decoder) appendHeader(tok xml.Token) {
+	if len(d.path) == 0 || (len(d.path) > 1 && d.path[1] == "server") {
+		return
+	}
+	d.header = append(d.header, tok)
+}
+
+// decodeHeader decodes the retained tokens into the server-level fields of
+// stats.
+func (d *Decoder) decodeHeader() error {
+	var s Stats
+	if err := xml.NewTokenDecoder(&tokenReader{tokens: d.header}).Decode(&s); err != nil {
+		return err
+	}
+	s.Applications = d.stats.Applications
+	s.DecodeWarnings.merge(d.stats.DecodeWarnings)
+	d.stats = s
+	d.header = nil
+	return nil
+}
+
+// tokenReader is an xml.TokenReader over a slice of tokens.
+type tokenReader struct {
+	tokens []xml.Token
+	pos    int
+
+	// Indexes of the start tokens of the open elements, and the range of
+	// tokens of the most recently closed element.
+	open               []int
+	lastStart, lastEnd int
+}
+
+func (r *tokenReader) Token() (xml.Token, error) {
+	if r.pos >= len(r.tokens) {
+		return nil, io.EOF
+	}
+	tok := r.tokens[r.pos]
+	switch tok.(type) {
+	case xml.StartElement:
+		r.open = append(r.open, r.pos)
+	case xml.EndElement:
+		if len(r.open) > 0 {
+			r.lastStart, r.lastEnd = r.open[len(r.open)-1], r.pos+1
+			r.open = r.open[:len(r.open)-1]
+		}
+	}
+	r.pos++
+	return tok, nil
+}
+
+// DecodeWarnings counts the elements that were skipped while decoding because
+// they couldn't be parsed, keyed by the type of element: stream, client,
+// duration, time, boolean, or number. Skipped values are left as zero.
+type DecodeWarnings map[string]int
+
+// add increments the count of element by n.
+func (w *DecodeWarnings) add(element string, n int) {
+	if n == 0 {
+		return
+	}
+	if *w == nil {
+		*w = make(DecodeWarnings)
+	}
+	(*w)[element] += n
+}
+
+// merge adds the counts of other to w.
+func (w *DecodeWarnings) merge(other DecodeWarnings) {
+	for element, n := range other {
+		w.add(element, n)
+	}
+}
+
+// readElement reads the remaining tokens of the element beginning with start
+// from d, returning them after start.
+func readElement(d *xml.Decoder, start xml.StartElement) ([]xml.Token, error) {
+	tokens := []xml.Token{start.Copy()}
+	for depth := 1; depth > 0; {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch tok.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+		tokens = append(tokens, xml.CopyToken(tok))
+	}
+	return tokens, nil
+}
+
+// decodeTolerant decodes the element in tokens into v, which must be a
+// pointer to a struct without an UnmarshalXML method. Elements that fail to
+// decode with a decodeWarning are removed and decoding is retried from
+// scratch, counting each removed element in w.
+func decodeTolerant(tokens []xml.Token, v interface{}, w *DecodeWarnings) error {
+	for {
+		r := &tokenReader{tokens: tokens}
+		err := xml.NewTokenDecoder(r).Decode(v)
+
+		// The failed element is the one most recently closed, as elements
+		// are always read up to their end before being parsed. The root
+		// element itself can't be removed.
+		var warn *decodeWarning
+		if !errors.As(err, &warn) || r.lastStart == 0 {
+			return err
+		}
+		w.add(warn.element, 1)
+
+		tokens = append(tokens[:r.lastStart:r.lastStart], tokens[r.lastEnd:]...)
+		rv := reflect.ValueOf(v).Elem()
+		rv.Set(reflect.Zero(rv.Type()))
+	}
+}
@@ -0,0 +1,91 @@
+package rtmpstats
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecoder(t *testing.T) {
+	f, err := os.Open("testdata/stats.xml")
+	require.NoError(t, err)
+	defer f.Close()
+
+	dec := NewDecoder(f)
+
+	stream, err := dec.Next()
+	require.NoError(t, err)
+	require.Equal(t, "live", dec.Application())
+	require.Equal(t, "streamName", stream.Name)
+	require.Equal(t, int64(2333128), stream.BitrateIn)
+	require.Len(t, stream.Clients, 4)
+
+	_, err = dec.Next()
+	require.Equal(t, io.EOF, err)
+	_, err = dec.Next()
+	require.Equal(t, io.EOF, err)
+
+	s := dec.Stats()
+	require.Equal(t, "1.19.0", s.NGINXVersion)
+	require.Equal(t, 93879*time.Second, s.Uptime)
+	require.Equal(t, int64(2338696), s.BitrateIn)
+	require.Equal(t, []Application{{Name: "live"}}, s.Applications)
+}
+
+func TestDecoder_MultipleApplications(t *testing.T) {
+	doc := `<?xml version="1.0" encoding="utf-8" ?>
+<rtmp>
+  <pid>13</pid>
+  <server>
+    <application>
+      <name>empty</name>
+      <live><nclients>0</nclients></live>
+    </application>
+    <application>
+      <name>live</name>
+      <live>
+        <stream><name>a</name><bw_in>10K</bw_in></stream>
+        <stream><name>b</name></stream>
+      </live>
+    </application>
+  </server>
+  <bytes_in>100</bytes_in>
+</rtmp>`
+
+	dec := NewDecoder(strings.NewReader(doc))
+
+	var names []string
+	for {
+		stream, err := dec.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		names = append(names, dec.Application()+"/"+stream.Name)
+	}
+	require.Equal(t, []string{"live/a", "live/b"}, names)
+
+	s := dec.Stats()
+	require.Equal(t, 13, s.PID)
+	require.Equal(t, int64(100), s.BytesIn)
+	require.Equal(t, []Application{{Name: "empty"}, {Name: "live"}}, s.Applications)
+
+	full, err := Unmarshal(strings.NewReader(doc))
+	require.NoError(t, err)
+	require.Equal(t, "empty", full.Applications[0].Name)
+	require.Empty(t, full.Applications[0].Streams)
+	require.Equal(t, int64(10000), full.Applications[1].Streams[0].BitrateIn)
+	require.Len(t, full.Applications[1].Streams, 2)
+}
+
+func TestDecoder_Truncated(t *testing.T) {
+	_, err := Unmarshal(strings.NewReader(`<rtmp><server><application><name>live</name>`))
+	require.Error(t, err)
+
+	_, err = Unmarshal(strings.NewReader(""))
+	require.Equal(t, io.EOF, err)
+}
@@ -2,6 +2,10 @@ package rtmpstats
 
 import (
 	"encoding/xml"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -14,50 +18,168 @@ import (
 // the final type, but override fields with one of these types. See Stats.UnmarshalXML
 // for an example of this in action.
 
+// decodeWarning is returned when an element of the given type can't be
+// decoded. Tolerant decoding skips the element rather than failing.
+type decodeWarning struct {
+	element string
+	err     error
+}
+
+func (w *decodeWarning) Error() string { return w.err.Error() }
+func (w *decodeWarning) Unwrap() error { return w.err }
+
 // Time is a time.Time that unmarshals correctly using nginx_rtmp_module's time format.
+// RFC3339 and RFC1123 times emitted by some forks are also accepted.
 type Time time.Time
 
+// timeLayouts are the layouts accepted by Time, in the order they're tried.
+var timeLayouts = []string{
+	"Jan _2 2006 15:04:05",
+	time.RFC3339,
+	time.RFC1123,
+}
+
 func (t *Time) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 	var timeStr string
 	if err := d.DecodeElement(&timeStr, &start); err != nil {
 		return err
 	}
 
-	parsedTime, err := time.Parse("Jan _2 2006 15:04:05", timeStr)
+	for _, layout := range timeLayouts {
+		if parsedTime, err := time.Parse(layout, timeStr); err == nil {
+			*t = Time(parsedTime)
+			return nil
+		}
+	}
+	return &decodeWarning{element: "time", err: fmt.Errorf("invalid time %q", timeStr)}
+}
+
+// Duration is a time.Duration that unmarshals correctly using
+// nginx_rtmp_module's duration format (milliseconds). Go-style durations
+// (e.g., 1h2m3s) emitted by some forks are also accepted.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
+	parsed, err := decodeDuration(dec, start, time.Millisecond)
 	if err != nil {
 		return err
 	}
 
-	*t = Time(parsedTime)
+	*d = Duration(parsed)
 	return nil
 }
 
-// Duration is a time.Duration that unmarshals correctly using
-// nginx_rtmp_module's duration format (milliseconds).
-type Duration time.Duration
+// SecondsDuration is a time.Duration that unmarshals from a number of seconds,
+// used by nginx_rtmp_module for the server uptime. Go-style durations (e.g.,
+// 26h) emitted by some forks are also accepted.
+type SecondsDuration time.Duration
 
-func (d *Duration) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
-	var ms int
-	if err := dec.DecodeElement(&ms, &start); err != nil {
+func (d *SecondsDuration) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
+	parsed, err := decodeDuration(dec, start, time.Second)
+	if err != nil {
 		return err
 	}
 
-	*d = Duration(time.Millisecond * time.Duration(ms))
+	*d = SecondsDuration(parsed)
 	return nil
 }
 
+// decodeDuration decodes an element as a Go-style duration string, falling
+// back to an integer multiple of unit.
+func decodeDuration(dec *xml.Decoder, start xml.StartElement, unit time.Duration) (time.Duration, error) {
+	var str string
+	if err := dec.DecodeElement(&str, &start); err != nil {
+		return 0, err
+	}
+
+	str = strings.TrimSpace(str)
+	if str == "" {
+		return 0, nil
+	}
+
+	if d, err := time.ParseDuration(str); err == nil {
+		return d, nil
+	}
+
+	n, err := strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		return 0, &decodeWarning{element: "duration", err: fmt.Errorf("invalid duration %q", str)}
+	}
+	return time.Duration(n) * unit, nil
+}
+
 // Boolean is a bool that is true if UnmarshalXML is called. It's intended
-// for self-closing tags where their presence indicate truthiness.
+// for self-closing tags where their presence indicate truthiness. Some forks
+// emit the value as text instead, so 1/0, true/false, and yes/no
+// (case-insensitive) are also accepted.
 type Boolean bool
 
 func (b *Boolean) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
-	*b = true
+	var str string
+	if err := dec.DecodeElement(&str, &start); err != nil {
+		return err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(str)) {
+	case "", "1", "true", "yes":
+		*b = true
+	case "0", "false", "no":
+		*b = false
+	default:
+		return &decodeWarning{element: "boolean", err: fmt.Errorf("invalid boolean %q", str)}
+	}
+	return nil
+}
+
+// Number is an int64 that unmarshals from a plain integer or from a decimal
+// value with a K, M, or G suffix (case-insensitive, scaled by powers of 1000)
+// and an optional trailing "bps" unit, e.g., 130M or 2.3Mbps. Some gateways
+// reformat nginx_rtmp_module's counters this way.
+type Number int64
+
+func (n *Number) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
+	var str string
+	if err := dec.DecodeElement(&str, &start); err != nil {
+		return err
+	}
+
+	parsed, err := parseNumber(str)
+	if err != nil {
+		return &decodeWarning{element: "number", err: err}
+	}
+
+	*n = Number(parsed)
+	return nil
+}
+
+var numberScales = map[byte]float64{
+	'k': 1e3,
+	'm': 1e6,
+	'g': 1e9,
+}
+
+// parseNumber parses str as a plain integer, falling back to a decimal value
+// with an optional unit suffix.
+func parseNumber(str string) (int64, error) {
+	str = strings.TrimSpace(str)
+	if str == "" {
+		return 0, nil
+	}
+	if n, err := strconv.ParseInt(str, 10, 64); err == nil {
+		return n, nil
+	}
 
-	// We still need to consume the element so we'll pretend to parse
-	// it here.
-	var consume bool
-	err := dec.DecodeElement(&dec, &start)
-	_ = consume
+	num := strings.TrimSuffix(strings.ToLower(str), "bps")
+	scale := 1.0
+	if len(num) > 0 {
+		if s, ok := numberScales[num[len(num)-1]]; ok {
+			num, scale = num[:len(num)-1], s
+		}
+	}
 
-	return err
+	f, err := strconv.ParseFloat(strings.TrimSpace(num), 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return 0, fmt.Errorf("invalid number %q", str)
+	}
+	return int64(math.Round(f * scale)), nil
 }
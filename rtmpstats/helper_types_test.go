@@ -0,0 +1,111 @@
+package rtmpstats
+
+import (
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDuration(t *testing.T) {
+	tt := []struct {
+		input  string
+		expect time.Duration
+	}{
+		{input: "<d>500003</d>", expect: 500003 * time.Millisecond},
+		{input: "<d>0</d>", expect: 0},
+		{input: "<d></d>", expect: 0},
+		{input: "<d>1h2m3s</d>", expect: time.Hour + 2*time.Minute + 3*time.Second},
+		{input: "<d> 250ms </d>", expect: 250 * time.Millisecond},
+	}
+
+	for _, tc := range tt {
+		var d Duration
+		require.NoError(t, xml.Unmarshal([]byte(tc.input), &d), tc.input)
+		require.Equal(t, tc.expect, time.Duration(d), tc.input)
+	}
+
+	var d Duration
+	require.EqualError(t, xml.Unmarshal([]byte("<d>soon</d>"), &d), `invalid duration "soon"`)
+}
+
+func TestSecondsDuration(t *testing.T) {
+	tt := []struct {
+		input  string
+		expect time.Duration
+	}{
+		{input: "<d>93879</d>", expect: 93879 * time.Second},
+		{input: "<d>26h</d>", expect: 26 * time.Hour},
+	}
+
+	for _, tc := range tt {
+		var d SecondsDuration
+		require.NoError(t, xml.Unmarshal([]byte(tc.input), &d), tc.input)
+		require.Equal(t, tc.expect, time.Duration(d), tc.input)
+	}
+}
+
+func TestBoolean(t *testing.T) {
+	tt := []struct {
+		input  string
+		expect bool
+	}{
+		{input: "<b/>", expect: true},
+		{input: "<b></b>", expect: true},
+		{input: "<b>1</b>", expect: true},
+		{input: "<b>0</b>", expect: false},
+		{input: "<b>true</b>", expect: true},
+		{input: "<b>False</b>", expect: false},
+		{input: "<b>YES</b>", expect: true},
+		{input: "<b> no </b>", expect: false},
+	}
+
+	for _, tc := range tt {
+		var b Boolean
+		require.NoError(t, xml.Unmarshal([]byte(tc.input), &b), tc.input)
+		require.Equal(t, tc.expect, bool(b), tc.input)
+	}
+
+	var b Boolean
+	require.EqualError(t, xml.Unmarshal([]byte("<b>maybe</b>"), &b), `invalid boolean "maybe"`)
+
+	// The element must be consumed up to its end tag so that later siblings
+	// are still decoded, and absent elements are false.
+	var doc struct {
+		Flag  Boolean `xml:"flag"`
+		Other Boolean `xml:"other"`
+		Name  string  `xml:"name"`
+	}
+	require.NoError(t, xml.Unmarshal([]byte(`<d><flag><nested>0</nested></flag><name>after</name></d>`), &doc))
+	require.True(t, bool(doc.Flag))
+	require.False(t, bool(doc.Other))
+	require.Equal(t, "after", doc.Name)
+}
+
+func TestNumber(t *testing.T) {
+	tt := []struct {
+		input  string
+		expect int64
+	}{
+		{input: "<n>130057972</n>", expect: 130057972},
+		{input: "<n></n>", expect: 0},
+		{input: "<n>130M</n>", expect: 130000000},
+		{input: "<n>2.3Mbps</n>", expect: 2300000},
+		{input: "<n>512k</n>", expect: 512000},
+		{input: "<n>1.5G</n>", expect: 1500000000},
+		{input: "<n>800bps</n>", expect: 800},
+		{input: "<n> 7 Kbps </n>", expect: 7000},
+		{input: "<n>5000000000</n>", expect: 5000000000},
+		{input: "<n>4.5G</n>", expect: 4500000000},
+	}
+
+	for _, tc := range tt {
+		var n Number
+		require.NoError(t, xml.Unmarshal([]byte(tc.input), &n), tc.input)
+		require.Equal(t, tc.expect, int64(n), tc.input)
+	}
+
+	var n Number
+	require.EqualError(t, xml.Unmarshal([]byte("<n>lots</n>"), &n), `invalid number "lots"`)
+}
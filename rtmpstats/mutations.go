@@ -1,6 +1,12 @@
 package rtmpstats
 
-import "fmt"
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+)
 
 // It's common for RTMP servers to use special keys for pushing to a stream,
 // but operators might not want to expose those keys as labels in the exporter.
@@ -13,6 +19,14 @@ import "fmt"
 // Mutator is any function that mutates Stats.
 type Mutator func(s *Stats) error
 
+// NamedMutator is a Mutator along with the type of mutation it performs, such
+// as the type of the Rule it was created from. The type is only used to
+// describe the mutator.
+type NamedMutator struct {
+	Type    string
+	Mutator Mutator
+}
+
 // WithStreamMapper creates a Mutator that mutates a Stats, changing all stream
 // names with the result of the mapper function. Resulting streams must have unique
 // names. The mutator will fail if names are not unique post-mapping.
@@ -41,6 +55,177 @@ func WithStreamMapper(mapper func(in string) string) Mutator {
 	}
 }
 
+// WithStreamSuffixCollapse creates a Mutator that mutates a Stats, removing
+// the text matched by suffix from the names of all streams. suffix should be
+// anchored to the end of the name, such as _[0-9]+$ to collapse the renditions
+// channel_1 and channel_2 of an adaptive bitrate ladder into channel. Streams
+// of an application that end up with the same name are summed together using
+// Stream.Add.
+func WithStreamSuffixCollapse(suffix *regexp.Regexp) Mutator {
+	return func(s *Stats) error {
+		for i, app := range s.Applications {
+			collapsed := make([]Stream, 0, len(app.Streams))
+			streamLookup := make(map[string]int)
+
+			for _, stream := range app.Streams {
+				stream.Name = suffix.ReplaceAllString(stream.Name, "")
+
+				idx, found := streamLookup[stream.Name]
+				if !found {
+					streamLookup[stream.Name] = len(collapsed)
+					collapsed = append(collapsed, stream)
+					continue
+				}
+				collapsed[idx] = collapsed[idx].Add(stream)
+			}
+
+			s.Applications[i].Streams = collapsed
+		}
+		return nil
+	}
+}
+
+// WithApplicationMapper creates a Mutator that mutates a Stats, changing all
+// application names with the result of the mapper function. Resulting
+// applications must have unique names. The mutator will fail if names are not
+// unique post-mapping.
+func WithApplicationMapper(mapper func(in string) string) Mutator {
+	return func(s *Stats) error {
+		// Transformed set of applications. We don't transform in-place so an
+		// invalid mapping doesn't partially mutate the set.
+		transformed := make([]Application, 0, len(s.Applications))
+		appLookup := make(map[string]struct{})
+
+		for _, app := range s.Applications {
+			app.Name = mapper(app.Name)
+
+			if _, found := appLookup[app.Name]; found {
+				return fmt.Errorf("an application with the name %s already exists", app.Name)
+			}
+			appLookup[app.Name] = struct{}{}
+			transformed = append(transformed, app)
+		}
+
+		s.Applications = transformed
+		return nil
+	}
+}
+
+// WithApplicationRename creates a Mutator that mutates a Stats, renaming the
+// application named old to new. Like WithApplicationMapper, the mutator will
+// fail if an application named new already exists.
+func WithApplicationRename(old, new string) Mutator {
+	return WithApplicationMapper(func(in string) string {
+		if in == old {
+			return new
+		}
+		return in
+	})
+}
+
+// WithApplicationFilter creates a Mutator that mutates a Stats, removing all
+// applications for which keep returns false.
+func WithApplicationFilter(keep func(name string) bool) Mutator {
+	return func(s *Stats) error {
+		filtered := make([]Application, 0, len(s.Applications))
+		for _, app := range s.Applications {
+			if keep(app.Name) {
+				filtered = append(filtered, app)
+			}
+		}
+		s.Applications = filtered
+		return nil
+	}
+}
+
+// OthersName is the name of the synthetic application and stream that
+// WithApplicationLimit folds excess applications into.
+const OthersName = "__others__"
+
+// WithApplicationLimit creates a Mutator that mutates a Stats, keeping at most
+// n applications. When sortByBytes is true, applications are first sorted by
+// their total bytes read and sent in descending order; otherwise the original
+// order is used.
+//
+// All streams of the applications beyond the limit are summed into a single
+// stream named __others__ in an application named __others__. Meta
+// information and output stats are dropped from the folded stream since it may
+// be composed of streams with different encodings.
+func WithApplicationLimit(n int, sortByBytes bool) Mutator {
+	return func(s *Stats) error {
+		if n < 0 {
+			return fmt.Errorf("application limit must not be negative")
+		}
+		if len(s.Applications) <= n {
+			return nil
+		}
+
+		apps := make([]Application, len(s.Applications))
+		copy(apps, s.Applications)
+
+		if sortByBytes {
+			totalBytes := func(app Application) (total int64) {
+				for _, stream := range app.Streams {
+					total += stream.BytesIn + stream.BytesOut
+				}
+				return total
+			}
+			sort.SliceStable(apps, func(i, j int) bool {
+				return totalBytes(apps[i]) > totalBytes(apps[j])
+			})
+		}
+
+		others := Stream{Name: OthersName}
+		for _, app := range apps[n:] {
+			for _, stream := range app.Streams {
+				others = others.Add(stream)
+			}
+		}
+		clearMeta(&others)
+		others.HLS, others.DVR = nil, nil
+
+		s.Applications = append(apps[:n:n], Application{
+			Name:    OthersName,
+			Streams: []Stream{others},
+		})
+		return nil
+	}
+}
+
+// WithoutStreamMeta creates a Mutator that mutates a Stats, clearing the video
+// and audio meta information from all streams. This reduces the cardinality
+// of metrics built from meta information for operators who don't need it.
+func WithoutStreamMeta() Mutator {
+	return func(s *Stats) error {
+		for appIdx, app := range s.Applications {
+			for streamIdx := range app.Streams {
+				clearMeta(&s.Applications[appIdx].Streams[streamIdx])
+			}
+		}
+
+		return nil
+	}
+}
+
+// clearMeta clears the video and audio meta information from a stream.
+func clearMeta(stream *Stream) {
+	stream.VideoWidth = 0
+	stream.VideoHeight = 0
+	stream.VideoFramerate = 0
+	stream.VideoCodec = ""
+	stream.VideoProfile = ""
+	stream.VideoCompat = 0
+	stream.VideoLevel = 0
+
+	stream.AudioCodec = ""
+	stream.AudioProfile = ""
+	stream.AudioChannels = 0
+	stream.AudioSampleRate = 0
+
+	stream.VideoTracks = nil
+	stream.AudioTracks = nil
+}
+
 // WithClientMapper creates a Mutator that mutates a Stats, changing all client
 // names with the result of the mapper function. Mapper will be invoked with the
 // stream the client is in along with the input client ID. Resulting clients
@@ -48,32 +233,176 @@ func WithStreamMapper(mapper func(in string) string) Mutator {
 // client will hold the final result of entries that were aggregated together (1 if
 // no aggregation was performed).
 func WithClientMapper(mapper func(stream string, in string) string) Mutator {
+	return WithClientMapperFunc(mapper, Client.Add)
+}
+
+// WithClientMapperFunc is like WithClientMapper but aggregates clients that
+// have the same ID using the reducer function instead of Client.Add. The ID and
+// EntriesCount fields of the reducer's result are always set by the mutator.
+func WithClientMapperFunc(mapper func(stream string, in string) string, reducer func(a, b Client) Client) Mutator {
+	return func(s *Stats) error {
+		mapClients(s, func(stream string, c Client) string {
+			return mapper(stream, c.ID)
+		}, reducer)
+		return nil
+	}
+}
+
+// WithClientNameFromAddress creates a Mutator that mutates a Stats, changing
+// the ID of every client whose address is found in the names map to the
+// mapped name. Clients whose address is not in the map keep their original ID.
+// Addresses are compared after normalizing them with NormalizeAddress.
+// Clients that end up with the same ID will be aggregated together, following
+// the same rules as WithClientMapper.
+func WithClientNameFromAddress(names map[string]string) Mutator {
+	normalized := make(map[string]string, len(names))
+	for addr, name := range names {
+		normalized[NormalizeAddress(addr)] = name
+	}
+
+	return func(s *Stats) error {
+		mapClients(s, func(_ string, c Client) string {
+			if name, ok := normalized[NormalizeAddress(c.Address)]; ok {
+				return name
+			}
+			return c.ID
+		}, Client.Add)
+		return nil
+	}
+}
+
+// WithPublisherFromStreamName creates a Mutator that mutates a Stats, changing
+// the ID of every publishing client to the name of its stream. This keeps the
+// ID of a publisher stable when nginx assigns it a new ID on every reconnect.
+// Multiple publishers of the same stream will be aggregated together,
+// following the same rules as WithClientMapper.
+func WithPublisherFromStreamName() Mutator {
 	return func(s *Stats) error {
+		mapClients(s, func(stream string, c Client) string {
+			if c.Publishing {
+				return stream
+			}
+			return c.ID
+		}, Client.Add)
+		return nil
+	}
+}
+
+// WithMaxClientsPerAddress creates a Mutator that mutates a Stats, limiting
+// the number of viewers of each stream from the same address to n. When more
+// than n viewers of a stream share an address, the first n-1 are kept and the
+// rest are aggregated into a single client whose ID is the address, following
+// the same rules as WithClientMapper. Addresses are compared after normalizing
+// them with NormalizeAddress. Publishers and clients without an address are
+// never aggregated.
+func WithMaxClientsPerAddress(n int) Mutator {
+	return func(s *Stats) error {
+		if n < 1 {
+			return fmt.Errorf("max clients per address must be at least 1")
+		}
+
 		for appIdx, app := range s.Applications {
 			for streamIdx, stream := range app.Streams {
-				aggregated := make([]Client, 0, len(stream.Clients))
-				clientLookup := make(map[string]int)
-
-				for _, client := range stream.Clients {
-					client.ID = mapper(stream.Name, client.ID)
-
-					// If the client already exists in the map, two clients resulted
-					// in the same mapping and we need to aggregate them together
-					// now.
-					duplicateIdx, found := clientLookup[client.ID]
-					if !found {
-						clientLookup[client.ID] = len(aggregated)
-						aggregated = append(aggregated, client)
-						continue
+				total := make(map[string]int)
+				for _, c := range stream.Clients {
+					if !c.Publishing && c.Address != "" {
+						total[NormalizeAddress(c.Address)]++
 					}
-
-					aggregated[duplicateIdx] = aggregated[duplicateIdx].Add(client)
 				}
 
-				s.Applications[appIdx].Streams[streamIdx].Clients = aggregated
+				seen := make(map[string]int)
+				s.Applications[appIdx].Streams[streamIdx].Clients = aggregateClients(stream.Clients, func(c Client) string {
+					if c.Publishing || c.Address == "" {
+						return c.ID
+					}
+					addr := NormalizeAddress(c.Address)
+					if total[addr] <= n {
+						return c.ID
+					}
+					if seen[addr]++; seen[addr] < n {
+						return c.ID
+					}
+					return addr
+				}, Client.Add)
 			}
 		}
+		return nil
+	}
+}
 
+// WithNormalizedAddresses creates a Mutator that mutates a Stats, replacing
+// the address of every client with the result of NormalizeAddress.
+func WithNormalizedAddresses() Mutator {
+	return func(s *Stats) error {
+		for appIdx, app := range s.Applications {
+			for streamIdx, stream := range app.Streams {
+				for clientIdx, client := range stream.Clients {
+					s.Applications[appIdx].Streams[streamIdx].Clients[clientIdx].Address = NormalizeAddress(client.Address)
+				}
+			}
+		}
 		return nil
 	}
 }
+
+// NormalizeAddress returns addr without a port or the brackets around an IPv6
+// address. IP addresses are returned in their canonical form, keeping an IPv6
+// zone if present. Addresses that aren't IP addresses are returned without
+// their port but otherwise unchanged.
+func NormalizeAddress(addr string) string {
+	host := addr
+	if h, _, err := net.SplitHostPort(addr); err == nil {
+		host = h
+	} else if strings.HasPrefix(addr, "[") && strings.HasSuffix(addr, "]") {
+		host = addr[1 : len(addr)-1]
+	}
+
+	var zone string
+	if idx := strings.LastIndexByte(host, '%'); idx >= 0 {
+		host, zone = host[:idx], host[idx:]
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		host = ip.String()
+	}
+	return host + zone
+}
+
+// mapClients changes the ID of all clients in s with the result of the mapper
+// function, aggregating clients that result in the same ID with reducer.
+func mapClients(s *Stats, mapper func(stream string, c Client) string, reducer func(a, b Client) Client) {
+	for appIdx, app := range s.Applications {
+		for streamIdx, stream := range app.Streams {
+			s.Applications[appIdx].Streams[streamIdx].Clients = aggregateClients(stream.Clients, func(c Client) string {
+				return mapper(stream.Name, c)
+			}, reducer)
+		}
+	}
+}
+
+// aggregateClients returns clients with the ID of each changed to the result
+// of the mapper function, aggregating clients that result in the same ID with
+// reducer.
+func aggregateClients(clients []Client, mapper func(c Client) string, reducer func(a, b Client) Client) []Client {
+	aggregated := make([]Client, 0, len(clients))
+	clientLookup := make(map[string]int)
+
+	for _, client := range clients {
+		client.ID = mapper(client)
+
+		// If the client already exists in the map, two clients resulted in the
+		// same mapping and we need to aggregate them together now.
+		duplicateIdx, found := clientLookup[client.ID]
+		if !found {
+			clientLookup[client.ID] = len(aggregated)
+			aggregated = append(aggregated, client)
+			continue
+		}
+
+		existing := aggregated[duplicateIdx]
+		reduced := reducer(existing, client)
+		reduced.ID = client.ID
+		reduced.EntriesCount = existing.EntriesCount + client.EntriesCount
+		aggregated[duplicateIdx] = reduced
+	}
+	return aggregated
+}
@@ -1,6 +1,7 @@
 package rtmpstats
 
 import (
+	"regexp"
 	"strings"
 	"testing"
 	"time"
@@ -47,6 +48,153 @@ func TestWithStreamMapper(t *testing.T) {
 	})
 }
 
+func TestWithStreamSuffixCollapse(t *testing.T) {
+	input := &Stats{
+		Applications: []Application{{
+			Streams: []Stream{
+				{Name: "channel_1080", BitrateIn: 6000, BytesIn: 60, NumClients: 3, Uptime: time.Minute, Clients: []Client{{ID: "1", EntriesCount: 1}}},
+				{Name: "other", BitrateIn: 100},
+				{Name: "channel_720", BitrateIn: 3000, BytesIn: 30, NumClients: 2, Uptime: time.Hour, Clients: []Client{{ID: "2", EntriesCount: 1}}},
+				{Name: "channel_480", BitrateIn: 1000, BytesIn: 10, NumClients: 1, Uptime: time.Second},
+			},
+		}},
+	}
+
+	require.NoError(t, WithStreamSuffixCollapse(regexp.MustCompile(`_[0-9]+$`))(input))
+
+	expect := []Stream{
+		{
+			Name: "channel", BitrateIn: 10000, BytesIn: 100, NumClients: 6, Uptime: time.Hour,
+			Clients: []Client{{ID: "1", EntriesCount: 1}, {ID: "2", EntriesCount: 1}},
+		},
+		{Name: "other", BitrateIn: 100},
+	}
+	require.Equal(t, expect, input.Applications[0].Streams)
+}
+
+func TestWithApplicationRename(t *testing.T) {
+	t.Run("rename", func(t *testing.T) {
+		input := &Stats{
+			Applications: []Application{
+				{Name: "live"},
+				{Name: "vod"},
+			},
+		}
+
+		err := WithApplicationRename("live", "edge-1-live")(input)
+		require.NoError(t, err)
+
+		expect := []Application{
+			{Name: "edge-1-live"},
+			{Name: "vod"},
+		}
+		require.Equal(t, expect, input.Applications)
+	})
+
+	t.Run("collision", func(t *testing.T) {
+		input := &Stats{
+			Applications: []Application{
+				{Name: "live"},
+				{Name: "vod"},
+			},
+		}
+
+		err := WithApplicationRename("live", "vod")(input)
+		require.EqualError(t, err, "an application with the name vod already exists")
+		require.Equal(t, "live", input.Applications[0].Name, "failed rename shouldn't mutate stats")
+	})
+}
+
+func TestWithApplicationLimit(t *testing.T) {
+	newInput := func() *Stats {
+		return &Stats{
+			Applications: []Application{
+				{Name: "a", Streams: []Stream{{Name: "a1", BytesIn: 1, BytesOut: 1, NumClients: 1}}},
+				{Name: "b", Streams: []Stream{
+					{Name: "b1", BytesIn: 50, BytesOut: 50, NumClients: 2, Uptime: time.Minute, VideoCodec: "H264"},
+					{Name: "b2", BytesIn: 10, BytesOut: 0, NumClients: 1, Clients: []Client{{ID: "1"}}},
+				}},
+				{Name: "c", Streams: []Stream{{Name: "c1", BytesIn: 20, BytesOut: 20, NumClients: 3, Uptime: time.Hour}}},
+			},
+		}
+	}
+
+	t.Run("under limit", func(t *testing.T) {
+		input := newInput()
+		require.NoError(t, WithApplicationLimit(3, true)(input))
+		require.Equal(t, newInput(), input)
+	})
+
+	t.Run("original order", func(t *testing.T) {
+		input := newInput()
+		require.NoError(t, WithApplicationLimit(1, false)(input))
+
+		expect := []Application{
+			{Name: "a", Streams: []Stream{{Name: "a1", BytesIn: 1, BytesOut: 1, NumClients: 1}}},
+			{Name: OthersName, Streams: []Stream{{
+				Name:       OthersName,
+				Uptime:     time.Hour,
+				BytesIn:    80,
+				BytesOut:   70,
+				NumClients: 6,
+				Clients:    []Client{{ID: "1"}},
+			}}},
+		}
+		require.Equal(t, expect, input.Applications)
+	})
+
+	t.Run("sorted by bytes", func(t *testing.T) {
+		input := newInput()
+		require.NoError(t, WithApplicationLimit(2, true)(input))
+
+		var names []string
+		for _, app := range input.Applications {
+			names = append(names, app.Name)
+		}
+		require.Equal(t, []string{"b", "c", OthersName}, names)
+
+		others := input.Applications[2].Streams
+		require.Len(t, others, 1)
+		require.Equal(t, int64(1), others[0].BytesIn)
+		require.Equal(t, 1, others[0].NumClients)
+	})
+
+	t.Run("negative", func(t *testing.T) {
+		err := WithApplicationLimit(-1, false)(newInput())
+		require.EqualError(t, err, "application limit must not be negative")
+	})
+}
+
+func TestWithoutStreamMeta(t *testing.T) {
+	input := &Stats{
+		Applications: []Application{{
+			Streams: []Stream{{
+				Name:            "stream",
+				BitrateIn:       100,
+				VideoWidth:      1920,
+				VideoHeight:     1080,
+				VideoFramerate:  30,
+				VideoCodec:      "H264",
+				VideoProfile:    "High",
+				VideoCompat:     1,
+				VideoLevel:      4,
+				AudioCodec:      "AAC",
+				AudioProfile:    "LC",
+				AudioChannels:   2,
+				AudioSampleRate: 44100,
+				VideoTracks:     []VideoTrack{{Codec: "H264"}},
+				AudioTracks:     []AudioTrack{{Codec: "AAC"}},
+			}},
+		}},
+	}
+
+	err := WithoutStreamMeta()(input)
+	require.NoError(t, err)
+
+	expect := []Stream{{Name: "stream", BitrateIn: 100}}
+	require.Equal(t, expect, input.Applications[0].Streams)
+}
+
 func TestWithClientMapper(t *testing.T) {
 	input := &Stats{
 		Applications: []Application{{
@@ -137,3 +285,160 @@ func TestWithClientMapper(t *testing.T) {
 	}
 	require.Equal(t, expect, input.Applications[0].Streams[0].Clients)
 }
+
+func TestWithClientMapperFunc(t *testing.T) {
+	input := &Stats{
+		Applications: []Application{{
+			Streams: []Stream{{
+				Name: "stream",
+				Clients: []Client{
+					{ID: "a", DroppedFrames: 150, Uptime: time.Minute, EntriesCount: 1},
+					{ID: "b", DroppedFrames: 50, Uptime: time.Second, EntriesCount: 1},
+					{ID: "c", DroppedFrames: 10, Uptime: time.Hour, EntriesCount: 1},
+				},
+			}},
+		}},
+	}
+
+	combineAll := func(_ string, _ string) string { return "all" }
+
+	// Keep the minimum uptime and the maximum dropped frames.
+	reducer := func(a, b Client) Client {
+		res := a
+		if b.Uptime < res.Uptime {
+			res.Uptime = b.Uptime
+		}
+		if b.DroppedFrames > res.DroppedFrames {
+			res.DroppedFrames = b.DroppedFrames
+		}
+		return res
+	}
+
+	err := WithClientMapperFunc(combineAll, reducer)(input)
+	require.NoError(t, err)
+
+	expect := []Client{
+		{ID: "all", DroppedFrames: 150, Uptime: time.Second, EntriesCount: 3},
+	}
+	require.Equal(t, expect, input.Applications[0].Streams[0].Clients)
+}
+
+func TestWithClientNameFromAddress(t *testing.T) {
+	input := &Stats{
+		Applications: []Application{{
+			Streams: []Stream{{
+				Name: "stream",
+				Clients: []Client{
+					{ID: "1", Address: "10.0.0.1:1935", DroppedFrames: 10, Publishing: true, EntriesCount: 1},
+					{ID: "2", Address: "10.0.0.2", DroppedFrames: 5, Active: true, EntriesCount: 1},
+					{ID: "3", Address: "10.0.0.3", DroppedFrames: 1, EntriesCount: 1},
+				},
+			}},
+		}},
+	}
+
+	names := map[string]string{
+		"10.0.0.1":       "studio",
+		"10.0.0.2:50000": "studio",
+	}
+	err := WithClientNameFromAddress(names)(input)
+	require.NoError(t, err)
+
+	expect := []Client{
+		{ID: "studio", Address: "10.0.0.1:1935", DroppedFrames: 15, Active: true, Publishing: true, EntriesCount: 2},
+		{ID: "3", Address: "10.0.0.3", DroppedFrames: 1, EntriesCount: 1},
+	}
+	require.Equal(t, expect, input.Applications[0].Streams[0].Clients)
+}
+
+func TestWithPublisherFromStreamName(t *testing.T) {
+	input := &Stats{
+		Applications: []Application{{
+			Streams: []Stream{{
+				Name: "stream",
+				Clients: []Client{
+					{ID: "1532", Publishing: true, EntriesCount: 1},
+					{ID: "2", EntriesCount: 1},
+				},
+			}},
+		}},
+	}
+
+	require.NoError(t, WithPublisherFromStreamName()(input))
+
+	expect := []Client{
+		{ID: "stream", Publishing: true, EntriesCount: 1},
+		{ID: "2", EntriesCount: 1},
+	}
+	require.Equal(t, expect, input.Applications[0].Streams[0].Clients)
+}
+
+func TestWithMaxClientsPerAddress(t *testing.T) {
+	input := &Stats{
+		Applications: []Application{{
+			Streams: []Stream{{
+				Name: "stream",
+				Clients: []Client{
+					{ID: "1", Address: "10.0.0.1", Publishing: true, EntriesCount: 1},
+					{ID: "2", Address: "10.0.0.1:50001", DroppedFrames: 1, EntriesCount: 1},
+					{ID: "3", Address: "10.0.0.2", EntriesCount: 1},
+					{ID: "4", Address: "10.0.0.1:50002", DroppedFrames: 2, EntriesCount: 1},
+					{ID: "5", Address: "10.0.0.1:50003", DroppedFrames: 4, EntriesCount: 1},
+					{ID: "6", Address: "10.0.0.2", EntriesCount: 1},
+				},
+			}},
+		}},
+	}
+
+	require.NoError(t, WithMaxClientsPerAddress(2)(input))
+
+	expect := []Client{
+		{ID: "1", Address: "10.0.0.1", Publishing: true, EntriesCount: 1},
+		{ID: "2", Address: "10.0.0.1:50001", DroppedFrames: 1, EntriesCount: 1},
+		{ID: "3", Address: "10.0.0.2", EntriesCount: 1},
+		{ID: "10.0.0.1", Address: "10.0.0.1:50002", DroppedFrames: 6, EntriesCount: 2},
+		{ID: "6", Address: "10.0.0.2", EntriesCount: 1},
+	}
+	require.Equal(t, expect, input.Applications[0].Streams[0].Clients)
+
+	require.Error(t, WithMaxClientsPerAddress(0)(input))
+}
+
+func TestNormalizeAddress(t *testing.T) {
+	tt := []struct {
+		in, expect string
+	}{
+		{in: "1.2.3.4", expect: "1.2.3.4"},
+		{in: "1.2.3.4:554", expect: "1.2.3.4"},
+		{in: "2001:db8::1", expect: "2001:db8::1"},
+		{in: "[2001:db8::1]", expect: "2001:db8::1"},
+		{in: "[2001:DB8:0::1]:1935", expect: "2001:db8::1"},
+		{in: "[fe80::1%eth0]:1935", expect: "fe80::1%eth0"},
+		{in: "fe80::1%eth0", expect: "fe80::1%eth0"},
+		{in: "example.com:1935", expect: "example.com"},
+		{in: "", expect: ""},
+	}
+
+	for _, tc := range tt {
+		require.Equal(t, tc.expect, NormalizeAddress(tc.in), tc.in)
+	}
+}
+
+func TestWithNormalizedAddresses(t *testing.T) {
+	input := &Stats{
+		Applications: []Application{{
+			Streams: []Stream{{
+				Clients: []Client{
+					{ID: "1", Address: "1.2.3.4:554"},
+					{ID: "2", Address: "[2001:db8::1]:1935"},
+				},
+			}},
+		}},
+	}
+
+	require.NoError(t, WithNormalizedAddresses()(input))
+
+	clients := input.Applications[0].Streams[0].Clients
+	require.Equal(t, "1.2.3.4", clients[0].Address)
+	require.Equal(t, "2001:db8::1", clients[1].Address)
+}
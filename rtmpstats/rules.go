@@ -0,0 +1,167 @@
+package rtmpstats
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Rules is a pipeline of built-in mutators, typically loaded from a YAML
+// file:
+//
+//	rules:
+//	  - type: rename_streams
+//	    match: "(.*)_[0-9a-f]+"
+//	    replacement: "$1"
+//	  - type: drop_applications
+//	    match: "internal-.*"
+//	  - type: client_names_from_address
+//	    names: {10.0.0.1: studio}
+type Rules struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule configures a single built-in mutator. The fields used depend on Type:
+//
+//	rename_applications, rename_streams, rename_clients: Match, Replacement
+//	drop_applications: Match
+//	collapse_stream_suffix: Match
+//	client_names_from_address: Names
+//	application_limit: Limit, SortByBytes
+//	max_clients_per_address: Limit
+//	strip_stream_meta, normalize_addresses, publisher_from_stream_name: none
+//
+// Match is a regular expression that must fully match a name, except for
+// collapse_stream_suffix where it must match the end of a stream name.
+// Replacement may refer to groups of Match with $1 and similar.
+type Rule struct {
+	Type        string            `yaml:"type"`
+	Match       string            `yaml:"match,omitempty"`
+	Replacement string            `yaml:"replacement,omitempty"`
+	Names       map[string]string `yaml:"names,omitempty"`
+	Limit       int               `yaml:"limit,omitempty"`
+	SortByBytes bool              `yaml:"sort_by_bytes,omitempty"`
+}
+
+// LoadRules reads Rules from the YAML file at path and returns its mutators,
+// named after the type of their rule.
+func LoadRules(path string) ([]NamedMutator, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening rules file: %w", err)
+	}
+	defer f.Close()
+	return ParseRules(f)
+}
+
+// ParseRules reads Rules in YAML from r and returns its mutators in order,
+// named after the type of their rule. An error is returned if any rule is
+// invalid.
+func ParseRules(r io.Reader) ([]NamedMutator, error) {
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules Rules
+	if err := yaml.UnmarshalStrict(buf, &rules); err != nil {
+		return nil, fmt.Errorf("parsing rules: %w", err)
+	}
+
+	muts := make([]NamedMutator, 0, len(rules.Rules))
+	for i, rule := range rules.Rules {
+		mut, err := rule.Mutator()
+		if err != nil {
+			return nil, fmt.Errorf("rule %d (%s): %w", i, rule.Type, err)
+		}
+		muts = append(muts, NamedMutator{Type: rule.Type, Mutator: mut})
+	}
+	return muts, nil
+}
+
+// Mutator returns the mutator configured by r.
+func (r Rule) Mutator() (Mutator, error) {
+	switch r.Type {
+	case "rename_applications", "rename_streams", "rename_clients":
+		re, err := r.compileMatch()
+		if err != nil {
+			return nil, err
+		}
+		rename := func(in string) string {
+			if !re.MatchString(in) {
+				return in
+			}
+			return re.ReplaceAllString(in, r.Replacement)
+		}
+
+		switch r.Type {
+		case "rename_applications":
+			return WithApplicationMapper(rename), nil
+		case "rename_streams":
+			return WithStreamMapper(rename), nil
+		default:
+			return WithClientMapper(func(_ string, in string) string { return rename(in) }), nil
+		}
+
+	case "collapse_stream_suffix":
+		if r.Match == "" {
+			return nil, fmt.Errorf("match must be set")
+		}
+		re, err := regexp.Compile("(?:" + r.Match + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid match: %w", err)
+		}
+		return WithStreamSuffixCollapse(re), nil
+
+	case "drop_applications":
+		re, err := r.compileMatch()
+		if err != nil {
+			return nil, err
+		}
+		return WithApplicationFilter(func(name string) bool { return !re.MatchString(name) }), nil
+
+	case "client_names_from_address":
+		if len(r.Names) == 0 {
+			return nil, fmt.Errorf("names must be set")
+		}
+		return WithClientNameFromAddress(r.Names), nil
+
+	case "application_limit":
+		if r.Limit < 0 {
+			return nil, fmt.Errorf("limit must not be negative")
+		}
+		return WithApplicationLimit(r.Limit, r.SortByBytes), nil
+
+	case "max_clients_per_address":
+		if r.Limit < 1 {
+			return nil, fmt.Errorf("limit must be at least 1")
+		}
+		return WithMaxClientsPerAddress(r.Limit), nil
+
+	case "strip_stream_meta":
+		return WithoutStreamMeta(), nil
+	case "normalize_addresses":
+		return WithNormalizedAddresses(), nil
+	case "publisher_from_stream_name":
+		return WithPublisherFromStreamName(), nil
+
+	default:
+		return nil, fmt.Errorf("unknown rule type %q", r.Type)
+	}
+}
+
+// compileMatch compiles Match anchored to fully match names.
+func (r Rule) compileMatch() (*regexp.Regexp, error) {
+	if r.Match == "" {
+		return nil, fmt.Errorf("match must be set")
+	}
+	re, err := regexp.Compile("^(?:" + r.Match + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid match: %w", err)
+	}
+	return re, nil
+}
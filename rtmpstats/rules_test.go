@@ -0,0 +1,89 @@
+package rtmpstats
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadRules(t *testing.T) {
+	muts, err := LoadRules("testdata/rules.yaml")
+	require.NoError(t, err)
+	require.Len(t, muts, 4)
+	require.Equal(t, "rename_streams", muts[0].Type)
+	require.Equal(t, "client_names_from_address", muts[3].Type)
+
+	s := &Stats{
+		Applications: []Application{
+			{
+				Name: "live",
+				Streams: []Stream{{
+					Name: "main_1a2b3c",
+					Clients: []Client{
+						{ID: "1", Address: "10.0.0.1", Publishing: true, EntriesCount: 1},
+						{ID: "2", Address: "10.0.0.2", EntriesCount: 1},
+					},
+				}},
+			},
+			{Name: "internal-relay"},
+			{Name: "playback"},
+		},
+	}
+	for _, mut := range muts {
+		require.NoError(t, mut.Mutator(s))
+	}
+
+	expect := &Stats{
+		Applications: []Application{
+			{
+				Name: "broadcast",
+				Streams: []Stream{{
+					Name: "main",
+					Clients: []Client{
+						{ID: "studio", Address: "10.0.0.1", Publishing: true, EntriesCount: 1},
+						{ID: "2", Address: "10.0.0.2", EntriesCount: 1},
+					},
+				}},
+			},
+			{Name: "playback", Streams: []Stream{}},
+		},
+	}
+	require.Equal(t, expect, s)
+}
+
+func TestParseRules_CollapseStreamSuffix(t *testing.T) {
+	muts, err := ParseRules(strings.NewReader("rules: [{type: collapse_stream_suffix, match: '_[0-9]+'}]"))
+	require.NoError(t, err)
+
+	s := &Stats{Applications: []Application{{
+		Streams: []Stream{{Name: "channel_1"}, {Name: "channel_2"}, {Name: "channel_2b"}},
+	}}}
+	require.NoError(t, muts[0].Mutator(s))
+
+	// The suffix is only removed from the end of the name.
+	require.Len(t, s.Applications[0].Streams, 2)
+	require.Equal(t, "channel", s.Applications[0].Streams[0].Name)
+	require.Equal(t, "channel_2b", s.Applications[0].Streams[1].Name)
+}
+
+func TestParseRules_Invalid(t *testing.T) {
+	tt := []struct {
+		name, input, expectErr string
+	}{
+		{name: "unknown type", input: "rules: [{type: shuffle}]", expectErr: `rule 0 (shuffle): unknown rule type "shuffle"`},
+		{name: "invalid regex", input: "rules: [{type: rename_streams, match: '('}]", expectErr: "rule 0 (rename_streams): invalid match"},
+		{name: "missing match", input: "rules: [{type: drop_applications}]", expectErr: "rule 0 (drop_applications): match must be set"},
+		{name: "missing names", input: "rules: [{type: strip_stream_meta}, {type: client_names_from_address}]", expectErr: "rule 1 (client_names_from_address): names must be set"},
+		{name: "missing limit", input: "rules: [{type: max_clients_per_address}]", expectErr: "rule 0 (max_clients_per_address): limit must be at least 1"},
+		{name: "unknown field", input: "rules: [{type: strip_stream_meta, regex: foo}]", expectErr: "parsing rules"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseRules(strings.NewReader(tc.input))
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.expectErr)
+		})
+	}
+}
@@ -5,6 +5,7 @@ package rtmpstats
 
 import (
 	"encoding/xml"
+	"fmt"
 	"io"
 	"time"
 )
@@ -18,34 +19,55 @@ type Stats struct {
 	PID              int           `xml:"pid"`
 	Uptime           time.Duration `xml:"uptime"`
 	Accepted         int           `xml:"naccepted"`
-	BitrateIn        int           `xml:"bw_in"`
-	BitrateOut       int           `xml:"bw_out"`
-	BytesIn          int           `xml:"bytes_in"`
-	BytesOut         int           `xml:"bytes_out"`
+	BitrateIn        int64         `xml:"bw_in"`
+	BitrateOut       int64         `xml:"bw_out"`
+	BytesIn          int64         `xml:"bytes_in"`
+	BytesOut         int64         `xml:"bytes_out"`
 	Applications     []Application `xml:"server>application"`
+
+	// DecodeWarnings counts the elements that were skipped while decoding,
+	// including those of streams and clients. Nil when nothing was skipped.
+	DecodeWarnings DecodeWarnings `xml:"-"`
 }
 
-// UnmarshalXML overrides the default unmarshaling behavior.
+// UnmarshalXML overrides the default unmarshaling behavior. Values that can't
+// be parsed and streams that can't be decoded are skipped and counted in
+// DecodeWarnings.
 func (s *Stats) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 	type plain Stats
 
 	stats := struct {
 		plain
-		Built  Time     `xml:"built"`
-		Uptime Duration `xml:"uptime"`
+		Built      Time            `xml:"built"`
+		Uptime     SecondsDuration `xml:"uptime"`
+		BitrateIn  Number          `xml:"bw_in"`
+		BitrateOut Number          `xml:"bw_out"`
+		BytesIn    Number          `xml:"bytes_in"`
+		BytesOut   Number          `xml:"bytes_out"`
 	}{}
 
-	if err := d.DecodeElement(&stats, &start); err != nil {
+	tokens, err := readElement(d, start)
+	if err != nil {
+		return err
+	}
+	var warnings DecodeWarnings
+	if err := decodeTolerant(tokens, &stats, &warnings); err != nil {
 		return err
 	}
 
 	*s = Stats(stats.plain)
+	for _, app := range s.Applications {
+		for _, stream := range app.Streams {
+			warnings.merge(stream.decodeWarnings)
+		}
+	}
+	s.DecodeWarnings = warnings
 	s.Built = time.Time(stats.Built)
-
-	// The stats uptime is actually server uptime in seconds. Change to
-	// seconds precision by dividing away the milliseconds and multipling
-	// the seconds.
-	s.Uptime = time.Duration(stats.Uptime) / time.Millisecond * time.Second
+	s.Uptime = time.Duration(stats.Uptime)
+	s.BitrateIn = int64(stats.BitrateIn)
+	s.BitrateOut = int64(stats.BitrateOut)
+	s.BytesIn = int64(stats.BytesIn)
+	s.BytesOut = int64(stats.BytesOut)
 	return nil
 }
 
@@ -55,20 +77,47 @@ type Application struct {
 	Streams []Stream `xml:"live>stream"`
 }
 
+// UnmarshalXML overrides the default unmarshaling behavior. The name of the
+// application is read from a name attribute when there's no name element.
+func (a *Application) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type plain Application
+
+	app := struct {
+		plain
+		NameAttr string `xml:"name,attr"`
+	}{}
+
+	if err := d.DecodeElement(&app, &start); err != nil {
+		return err
+	}
+
+	*a = Application(app.plain)
+	if a.Name == "" {
+		a.Name = app.NameAttr
+	}
+	return nil
+}
+
 // Stream holds stream-specific statistics.
 type Stream struct {
 	Name         string        `xml:"name"`
 	Uptime       time.Duration `xml:"time"`
-	BitrateIn    int           `xml:"bw_in"`
-	BitrateOut   int           `xml:"bw_out"`
-	BytesIn      int           `xml:"bytes_in"`
-	BytesOut     int           `xml:"bytes_out"`
-	BitrateVideo int           `xml:"bw_video"`
-	BitrateAudio int           `xml:"bw_audio"`
+	BitrateIn    int64         `xml:"bw_in"`
+	BitrateOut   int64         `xml:"bw_out"`
+	BytesIn      int64         `xml:"bytes_in"`
+	BytesOut     int64         `xml:"bytes_out"`
+	BitrateVideo int64         `xml:"bw_video"`
+	BitrateAudio int64         `xml:"bw_audio"`
 	NumClients   int           `xml:"nclients"`
 	Publishing   bool          `xml:"publishing"`
 	Active       bool          `xml:"active"`
 
+	// Subscriber and publisher counts reported alongside nclients by some
+	// forks of the module. Nil when not reported; use SubscriberCount and
+	// PublisherCount to fall back to counting Clients.
+	Subscribers *int `xml:"subscribers"`
+	Publishers  *int `xml:"publishers"`
+
 	// Meta information on Video
 	VideoWidth     int     `xml:"meta>video>width"`
 	VideoHeight    int     `xml:"meta>video>height"`
@@ -84,26 +133,203 @@ type Stream struct {
 	AudioChannels   int    `xml:"meta>audio>channels"`
 	AudioSampleRate int    `xml:"meta>audio>sample_rate"`
 
+	// Meta information on every video and audio track of the stream. The
+	// single-track fields above are set from the first track of each.
+	VideoTracks []VideoTrack `xml:"-"`
+	AudioTracks []AudioTrack `xml:"-"`
+
+	// Output stats, only set when the module exposes them for the stream.
+	HLS *HLS `xml:"hls"`
+	DVR *DVR `xml:"dvr"`
+
 	Clients []Client `xml:"client"`
+
+	// Elements skipped while decoding the stream and its clients.
+	decodeWarnings DecodeWarnings
+}
+
+// SubscriberCount returns the number of non-publishing clients of the stream.
+// The count reported by the server is preferred over counting Clients, which
+// may be truncated.
+func (s Stream) SubscriberCount() int {
+	if s.Subscribers != nil {
+		return *s.Subscribers
+	}
+	var n int
+	for _, c := range s.Clients {
+		if !c.Publishing {
+			n += c.EntriesCount
+		}
+	}
+	return n
+}
+
+// PublisherCount returns the number of publishing clients of the stream. The
+// count reported by the server is preferred over counting Clients, which may
+// be truncated.
+func (s Stream) PublisherCount() int {
+	if s.Publishers != nil {
+		return *s.Publishers
+	}
+	var n int
+	for _, c := range s.Clients {
+		if c.Publishing {
+			n += c.EntriesCount
+		}
+	}
+	return n
 }
 
-// UnmarshalXML overrides the default unmarshaling behavior.
+// VideoTrack holds meta information on a video track of a stream.
+type VideoTrack struct {
+	Width     int     `xml:"width"`
+	Height    int     `xml:"height"`
+	Framerate int     `xml:"frame_rate"`
+	Codec     string  `xml:"codec"`
+	Profile   string  `xml:"profile"`
+	Compat    int     `xml:"compat"`
+	Level     float64 `xml:"level"`
+}
+
+// AudioTrack holds meta information on an audio track of a stream.
+type AudioTrack struct {
+	Codec      string `xml:"codec"`
+	Profile    string `xml:"profile"`
+	Channels   int    `xml:"channels"`
+	SampleRate int    `xml:"sample_rate"`
+}
+
+// HLS holds stats on the HLS output of a stream.
+type HLS struct {
+	Fragments int `xml:"fragments"`
+	Sequence  int `xml:"sequence"`
+}
+
+// DVR holds stats on the recording output of a stream.
+type DVR struct {
+	Size int `xml:"size"`
+}
+
+// Add returns the result of summing the local stream with another stream.
+// Bitrates, byte counts, and client counts are summed and the clients of both
+// streams are combined. Booleans will be true if either value is true and the
+// longest uptime is used. Meta information and output stats are copied from
+// the source stream unless it has none. Reported subscriber and publisher
+// counts are only kept when both streams have them.
+func (s Stream) Add(other Stream) Stream {
+	res := s
+
+	if other.Uptime > res.Uptime {
+		res.Uptime = other.Uptime
+	}
+	res.BitrateIn += other.BitrateIn
+	res.BitrateOut += other.BitrateOut
+	res.BytesIn += other.BytesIn
+	res.BytesOut += other.BytesOut
+	res.BitrateVideo += other.BitrateVideo
+	res.BitrateAudio += other.BitrateAudio
+	res.NumClients += other.NumClients
+	res.Subscribers = addCounts(s.Subscribers, other.Subscribers)
+	res.Publishers = addCounts(s.Publishers, other.Publishers)
+	res.Publishing = s.Publishing || other.Publishing
+	res.Active = s.Active || other.Active
+
+	if res.VideoCodec == "" && res.AudioCodec == "" {
+		res.VideoWidth = other.VideoWidth
+		res.VideoHeight = other.VideoHeight
+		res.VideoFramerate = other.VideoFramerate
+		res.VideoCodec = other.VideoCodec
+		res.VideoProfile = other.VideoProfile
+		res.VideoCompat = other.VideoCompat
+		res.VideoLevel = other.VideoLevel
+		res.AudioCodec = other.AudioCodec
+		res.AudioProfile = other.AudioProfile
+		res.AudioChannels = other.AudioChannels
+		res.AudioSampleRate = other.AudioSampleRate
+		res.VideoTracks = other.VideoTracks
+		res.AudioTracks = other.AudioTracks
+	}
+
+	if res.HLS == nil {
+		res.HLS = other.HLS
+	}
+	if res.DVR == nil {
+		res.DVR = other.DVR
+	}
+
+	res.Clients = make([]Client, 0, len(s.Clients)+len(other.Clients))
+	res.Clients = append(res.Clients, s.Clients...)
+	res.Clients = append(res.Clients, other.Clients...)
+	return res
+}
+
+// addCounts returns the sum of two optional counts, or nil if either is
+// missing.
+func addCounts(a, b *int) *int {
+	if a == nil || b == nil {
+		return nil
+	}
+	sum := *a + *b
+	return &sum
+}
+
+// UnmarshalXML overrides the default unmarshaling behavior. Values that can't
+// be parsed and clients that can't be decoded are skipped.
 func (s *Stream) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 	type plain Stream
 
 	stats := struct {
 		plain
-		Uptime     Duration `xml:"time"`
-		Publishing Boolean  `xml:"publishing"`
-		Active     Boolean  `xml:"active"`
+		Uptime       Duration `xml:"time"`
+		BitrateIn    Number   `xml:"bw_in"`
+		BitrateOut   Number   `xml:"bw_out"`
+		BytesIn      Number   `xml:"bytes_in"`
+		BytesOut     Number   `xml:"bytes_out"`
+		BitrateVideo Number   `xml:"bw_video"`
+		BitrateAudio Number   `xml:"bw_audio"`
+		Publishing   Boolean  `xml:"publishing"`
+		Active       Boolean  `xml:"active"`
+
+		// Shallower than the single-track meta fields of plain, which are
+		// ignored by the decoder and set from the first tracks instead.
+		Meta struct {
+			Video []VideoTrack `xml:"video"`
+			Audio []AudioTrack `xml:"audio"`
+		} `xml:"meta"`
 	}{}
 
-	if err := d.DecodeElement(&stats, &start); err != nil {
+	tokens, err := readElement(d, start)
+	if err != nil {
 		return err
 	}
+	var warnings DecodeWarnings
+	if err := decodeTolerant(tokens, &stats, &warnings); err != nil {
+		return &decodeWarning{element: "stream", err: err}
+	}
 
 	*s = Stream(stats.plain)
+	for _, c := range s.Clients {
+		warnings.merge(c.decodeWarnings)
+	}
+	s.decodeWarnings = warnings
+	s.VideoTracks = stats.Meta.Video
+	s.AudioTracks = stats.Meta.Audio
+	if len(s.VideoTracks) > 0 {
+		v := s.VideoTracks[0]
+		s.VideoWidth, s.VideoHeight, s.VideoFramerate = v.Width, v.Height, v.Framerate
+		s.VideoCodec, s.VideoProfile, s.VideoCompat, s.VideoLevel = v.Codec, v.Profile, v.Compat, v.Level
+	}
+	if len(s.AudioTracks) > 0 {
+		a := s.AudioTracks[0]
+		s.AudioCodec, s.AudioProfile, s.AudioChannels, s.AudioSampleRate = a.Codec, a.Profile, a.Channels, a.SampleRate
+	}
 	s.Uptime = time.Duration(stats.Uptime)
+	s.BitrateIn = int64(stats.BitrateIn)
+	s.BitrateOut = int64(stats.BitrateOut)
+	s.BytesIn = int64(stats.BytesIn)
+	s.BytesOut = int64(stats.BytesOut)
+	s.BitrateVideo = int64(stats.BitrateVideo)
+	s.BitrateAudio = int64(stats.BitrateAudio)
 	s.Publishing = bool(stats.Publishing)
 	s.Active = bool(stats.Active)
 	return nil
@@ -127,6 +353,9 @@ type Client struct {
 	// together and this field will include how many duplicates there were. A
 	// value of 1 indicates that this is the only client with this ID.
 	EntriesCount int `xml:"-"`
+
+	// Elements skipped while decoding the client.
+	decodeWarnings DecodeWarnings
 }
 
 // Add returns the result of summing the local client with another client. The
@@ -160,7 +389,8 @@ func (c Client) Add(other Client) Client {
 	}
 }
 
-// UnmarshalXML overrides the default unmarshaling behavior.
+// UnmarshalXML overrides the default unmarshaling behavior. Values that can't
+// be parsed are skipped.
 func (c *Client) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 	type plain Client
 
@@ -172,11 +402,17 @@ func (c *Client) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 		Publishing Boolean  `xml:"publishing"`
 	}{}
 
-	if err := d.DecodeElement(&stats, &start); err != nil {
+	tokens, err := readElement(d, start)
+	if err != nil {
 		return err
 	}
+	var warnings DecodeWarnings
+	if err := decodeTolerant(tokens, &stats, &warnings); err != nil {
+		return &decodeWarning{element: "client", err: err}
+	}
 
 	*c = Client(stats.plain)
+	c.decodeWarnings = warnings
 	c.Uptime = time.Duration(stats.Uptime)
 	c.Timestamp = time.Duration(stats.Timestamp)
 	c.Active = bool(stats.Active)
@@ -188,18 +424,116 @@ func (c *Client) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 // Unmarshal unmarshals data from the given io.Reader into a Stats struct.
 // A set of mutators can optionally be applied at unmarshal time.
 func Unmarshal(r io.Reader, muts ...Mutator) (*Stats, error) {
+	dec := NewDecoder(r)
+	s := dec.Stats()
+
+	for {
+		stream, err := dec.Next()
+		if err == io.EOF {
+			if !dec.finished {
+				return nil, err
+			}
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		app := &s.Applications[len(s.Applications)-1]
+		app.Streams = append(app.Streams, *stream)
+	}
+
+	for _, mut := range muts {
+		if err := mut(s); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// UnmarshalAll unmarshals a sequence of concatenated stats documents from the
+// given io.Reader and merges them into a single Stats struct using Merge.
+// Mutators are applied to the merged result.
+func UnmarshalAll(r io.Reader, muts ...Mutator) (*Stats, error) {
 	dec := xml.NewDecoder(r)
 
-	var s Stats
-	if err := dec.Decode(&s); err != nil {
-		return nil, err
+	var docs []Stats
+	for {
+		var s Stats
+		err := dec.Decode(&s)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		docs = append(docs, s)
+	}
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("no stats documents found")
 	}
 
+	s := Merge(docs...)
 	for _, mut := range muts {
-		if err := mut(&s); err != nil {
+		if err := mut(s); err != nil {
 			return nil, err
 		}
 	}
 
-	return &s, nil
+	return s, nil
+}
+
+// Merge combines multiple Stats into one. Server-level counters, bitrates, and
+// decode warnings are summed and the longest uptime is kept. Build information
+// is taken from the first Stats. Applications are merged by name, and streams
+// with the same name within an application are summed together using
+// Stream.Add.
+func Merge(stats ...Stats) *Stats {
+	var res Stats
+	if len(stats) == 0 {
+		return &res
+	}
+
+	res.NGINXVersion = stats[0].NGINXVersion
+	res.NGINXRTMPVersion = stats[0].NGINXRTMPVersion
+	res.Compiler = stats[0].Compiler
+	res.Built = stats[0].Built
+	res.PID = stats[0].PID
+
+	appLookup := make(map[string]int)
+	streamLookup := make(map[string]map[string]int)
+
+	for _, s := range stats {
+		if s.Uptime > res.Uptime {
+			res.Uptime = s.Uptime
+		}
+		res.Accepted += s.Accepted
+		res.BitrateIn += s.BitrateIn
+		res.BitrateOut += s.BitrateOut
+		res.BytesIn += s.BytesIn
+		res.BytesOut += s.BytesOut
+		res.DecodeWarnings.merge(s.DecodeWarnings)
+
+		for _, app := range s.Applications {
+			appIdx, found := appLookup[app.Name]
+			if !found {
+				appIdx = len(res.Applications)
+				appLookup[app.Name] = appIdx
+				streamLookup[app.Name] = make(map[string]int)
+				res.Applications = append(res.Applications, Application{Name: app.Name})
+			}
+
+			merged := &res.Applications[appIdx]
+			for _, stream := range app.Streams {
+				streamIdx, found := streamLookup[app.Name][stream.Name]
+				if !found {
+					streamLookup[app.Name][stream.Name] = len(merged.Streams)
+					merged.Streams = append(merged.Streams, stream)
+					continue
+				}
+				merged.Streams[streamIdx] = merged.Streams[streamIdx].Add(stream)
+			}
+		}
+	}
+
+	return &res
 }
@@ -1,7 +1,11 @@
 package rtmpstats
 
 import (
+	"encoding/xml"
+	"io"
+	"math"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -58,6 +62,13 @@ func TestUnmarshal(t *testing.T) {
 				AudioChannels:   2,
 				AudioSampleRate: 44100,
 
+				VideoTracks: []VideoTrack{{
+					Width: 1920, Height: 1080, Framerate: 30, Codec: "H264", Profile: "High", Level: 4,
+				}},
+				AudioTracks: []AudioTrack{{
+					Codec: "AAC", Profile: "LC", Channels: 2, SampleRate: 44100,
+				}},
+
 				Clients: []Client{
 					{
 						ID:            "51",
@@ -115,3 +126,305 @@ func TestUnmarshal(t *testing.T) {
 	}
 	require.Equal(t, expect, s)
 }
+
+func TestUnmarshal_GoDurations(t *testing.T) {
+	unmarshalFile := func(path string) *Stats {
+		f, err := os.Open(path)
+		require.NoError(t, err)
+		defer f.Close()
+
+		s, err := Unmarshal(f)
+		require.NoError(t, err)
+		return s
+	}
+
+	numeric := unmarshalFile("testdata/stats.xml")
+	goDurations := unmarshalFile("testdata/stats_go_durations.xml")
+	require.Equal(t, numeric, goDurations)
+}
+
+func TestUnmarshal_BuiltLayouts(t *testing.T) {
+	expect := time.Date(2020, time.July, 11, 22, 3, 37, 0, time.UTC)
+
+	for _, path := range []string{
+		"testdata/stats.xml",
+		"testdata/stats_built_rfc3339.xml",
+		"testdata/stats_built_rfc1123.xml",
+	} {
+		f, err := os.Open(path)
+		require.NoError(t, err)
+
+		s, err := Unmarshal(f)
+		f.Close()
+		require.NoError(t, err, path)
+		require.True(t, expect.Equal(s.Built), "%s: got %s", path, s.Built)
+	}
+
+	s, err := Unmarshal(strings.NewReader("<rtmp><built>yesterday</built></rtmp>"))
+	require.NoError(t, err)
+	require.True(t, s.Built.IsZero())
+	require.Equal(t, DecodeWarnings{"time": 1}, s.DecodeWarnings)
+}
+
+func TestUnmarshal_LargeCounters(t *testing.T) {
+	doc := `<rtmp>
+  <bytes_in>5000000000</bytes_in>
+  <server><application><name>live</name><live>
+    <stream><name>a</name><bytes_out>9000000000</bytes_out></stream>
+  </live></application></server>
+</rtmp>`
+
+	s, err := Unmarshal(strings.NewReader(doc))
+	require.NoError(t, err)
+	require.Equal(t, int64(5000000000), s.BytesIn)
+	require.Equal(t, int64(9000000000), s.Applications[0].Streams[0].BytesOut)
+	require.True(t, s.BytesIn > math.MaxInt32)
+}
+
+func TestUnmarshal_DecodeWarnings(t *testing.T) {
+	f, err := os.Open("testdata/stats_malformed.xml")
+	require.NoError(t, err)
+	defer f.Close()
+
+	s, err := Unmarshal(f)
+	require.NoError(t, err)
+
+	expect := DecodeWarnings{"time": 1, "stream": 1, "duration": 1, "client": 1, "boolean": 1}
+	require.Equal(t, expect, s.DecodeWarnings)
+	require.True(t, s.Built.IsZero())
+	require.Equal(t, 13, s.PID)
+
+	require.Len(t, s.Applications[0].Streams, 1)
+	stream := s.Applications[0].Streams[0]
+	require.Equal(t, "streamName", stream.Name)
+	require.Equal(t, time.Duration(0), stream.Uptime)
+	require.Equal(t, int64(2333128), stream.BitrateIn)
+	require.Len(t, stream.Clients, 2)
+	require.Equal(t, "15", stream.Clients[0].ID)
+	require.False(t, stream.Clients[0].Active)
+	require.Equal(t, 371856*time.Millisecond, stream.Clients[0].Uptime)
+
+	_, err = f.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	all, err := UnmarshalAll(f)
+	require.NoError(t, err)
+	require.Equal(t, expect, all.DecodeWarnings)
+}
+
+func TestUnmarshal_ApplicationNameAttribute(t *testing.T) {
+	unmarshalFile := func(path string) *Stats {
+		f, err := os.Open(path)
+		require.NoError(t, err)
+		defer f.Close()
+
+		s, err := Unmarshal(f)
+		require.NoError(t, err)
+		return s
+	}
+
+	element := unmarshalFile("testdata/stats.xml")
+	attribute := unmarshalFile("testdata/stats_app_name_attr.xml")
+	require.Equal(t, "live", attribute.Applications[0].Name)
+	require.Equal(t, element, attribute)
+
+	var app Application
+	require.NoError(t, xml.Unmarshal([]byte(`<application name="attr"><name>element</name></application>`), &app))
+	require.Equal(t, "element", app.Name)
+	require.NoError(t, xml.Unmarshal([]byte(`<application name="attr"></application>`), &app))
+	require.Equal(t, "attr", app.Name)
+}
+
+func TestUnmarshal_SuffixedNumbers(t *testing.T) {
+	f, err := os.Open("testdata/stats_suffixed.xml")
+	require.NoError(t, err)
+	defer f.Close()
+
+	s, err := Unmarshal(f)
+	require.NoError(t, err)
+
+	require.Equal(t, int64(2300000), s.BitrateIn)
+	require.Equal(t, int64(130000000), s.BytesIn)
+	require.Equal(t, int64(7000000), s.BitrateOut)
+	require.Equal(t, int64(1200000000), s.BytesOut)
+
+	stream := s.Applications[0].Streams[0]
+	require.Equal(t, int64(2333128), stream.BitrateIn)
+	require.Equal(t, int64(129700000), stream.BytesIn)
+	require.Equal(t, int64(6999000), stream.BitrateOut)
+	require.Equal(t, int64(238916032), stream.BytesOut)
+	require.Equal(t, int64(106900), stream.BitrateAudio)
+	require.Equal(t, int64(2200000), stream.BitrateVideo)
+}
+
+func TestUnmarshal_TextBooleans(t *testing.T) {
+	f, err := os.Open("testdata/stats_text_booleans.xml")
+	require.NoError(t, err)
+	defer f.Close()
+
+	s, err := Unmarshal(f)
+	require.NoError(t, err)
+
+	stream := s.Applications[0].Streams[0]
+	require.True(t, stream.Publishing)
+	require.True(t, stream.Active)
+
+	for _, cli := range stream.Clients {
+		require.True(t, cli.Active, cli.ID)
+		require.Equal(t, cli.ID == "1", cli.Publishing, cli.ID)
+	}
+}
+
+func TestUnmarshal_FalseBooleans(t *testing.T) {
+	input := `<rtmp><server><application><name>live</name><live>
+		<stream>
+			<name>stream</name>
+			<client><id>1</id><publishing>no</publishing><active>false</active></client>
+			<publishing>0</publishing>
+			<active>FALSE</active>
+		</stream>
+	</live></application></server></rtmp>`
+
+	s, err := Unmarshal(strings.NewReader(input))
+	require.NoError(t, err)
+
+	stream := s.Applications[0].Streams[0]
+	require.False(t, stream.Publishing)
+	require.False(t, stream.Active)
+	require.False(t, stream.Clients[0].Publishing)
+	require.False(t, stream.Clients[0].Active)
+}
+
+func TestUnmarshal_Outputs(t *testing.T) {
+	f, err := os.Open("testdata/stats_outputs.xml")
+	require.NoError(t, err)
+	defer f.Close()
+
+	s, err := Unmarshal(f)
+	require.NoError(t, err)
+
+	stream := s.Applications[0].Streams[0]
+	require.Equal(t, &HLS{Fragments: 5, Sequence: 83}, stream.HLS)
+	require.Equal(t, &DVR{Size: 1048576}, stream.DVR)
+	require.Len(t, stream.Clients, 4)
+}
+
+func TestUnmarshal_MultiTrack(t *testing.T) {
+	f, err := os.Open("testdata/stats_multi_track.xml")
+	require.NoError(t, err)
+	defer f.Close()
+
+	s, err := Unmarshal(f)
+	require.NoError(t, err)
+
+	stream := s.Applications[0].Streams[0]
+	require.Equal(t, []VideoTrack{
+		{Width: 1920, Height: 1080, Framerate: 30, Codec: "H264", Profile: "High", Level: 4.2},
+		{Width: 1280, Height: 720, Framerate: 30, Codec: "H264", Profile: "Main", Level: 3.1},
+	}, stream.VideoTracks)
+	require.Equal(t, []AudioTrack{
+		{Codec: "AAC", Profile: "LC", Channels: 2, SampleRate: 48000},
+		{Codec: "AAC", Profile: "LC", Channels: 1, SampleRate: 44100},
+	}, stream.AudioTracks)
+
+	// Single-track fields come from the first tracks.
+	require.Equal(t, 1920, stream.VideoWidth)
+	require.Equal(t, "High", stream.VideoProfile)
+	require.Equal(t, 4.2, stream.VideoLevel)
+	require.Equal(t, 2, stream.AudioChannels)
+	require.Equal(t, 48000, stream.AudioSampleRate)
+}
+
+func TestStream_ClientCounts(t *testing.T) {
+	var stream Stream
+	err := xml.Unmarshal([]byte(`<stream>
+		<nclients>3</nclients>
+		<subscribers>20</subscribers>
+		<publishers>1</publishers>
+		<client><id>1</id><publishing/></client>
+		<client><id>2</id></client>
+	</stream>`), &stream)
+	require.NoError(t, err)
+	require.Equal(t, 20, stream.SubscriberCount())
+	require.Equal(t, 1, stream.PublisherCount())
+
+	stream.Subscribers, stream.Publishers = nil, nil
+	require.Equal(t, 1, stream.SubscriberCount())
+	require.Equal(t, 1, stream.PublisherCount())
+}
+
+func TestUnmarshalAll(t *testing.T) {
+	f, err := os.Open("testdata/stats_multi.xml")
+	require.NoError(t, err)
+	defer f.Close()
+
+	s, err := UnmarshalAll(f)
+	require.NoError(t, err)
+
+	require.Equal(t, 13, s.PID)
+	require.Equal(t, 93880*time.Second, s.Uptime)
+	require.Equal(t, 13, s.Accepted)
+	require.Equal(t, int64(2339696), s.BitrateIn)
+	require.Equal(t, int64(7018072), s.BitrateOut)
+	require.Equal(t, int64(130058000), s.BytesIn)
+	require.Equal(t, int64(239471000), s.BytesOut)
+
+	require.Len(t, s.Applications, 1)
+	require.Len(t, s.Applications[0].Streams, 2)
+	require.Equal(t, "streamName", s.Applications[0].Streams[0].Name)
+	require.Equal(t, "otherStream", s.Applications[0].Streams[1].Name)
+}
+
+func TestMerge(t *testing.T) {
+	a := Stats{
+		BytesIn: 10,
+		Applications: []Application{{
+			Name: "live",
+			Streams: []Stream{{
+				Name:       "stream",
+				Uptime:     time.Minute,
+				BytesIn:    5,
+				NumClients: 1,
+				Active:     true,
+				VideoCodec: "H264",
+				Clients:    []Client{{ID: "1"}},
+			}},
+		}},
+	}
+	b := Stats{
+		BytesIn: 20,
+		Applications: []Application{
+			{
+				Name: "live",
+				Streams: []Stream{{
+					Name:       "stream",
+					Uptime:     time.Hour,
+					BytesIn:    7,
+					NumClients: 2,
+					VideoCodec: "VP6",
+					Clients:    []Client{{ID: "2"}, {ID: "3"}},
+				}},
+			},
+			{Name: "other"},
+		},
+	}
+
+	expect := &Stats{
+		BytesIn: 30,
+		Applications: []Application{
+			{
+				Name: "live",
+				Streams: []Stream{{
+					Name:       "stream",
+					Uptime:     time.Hour,
+					BytesIn:    12,
+					NumClients: 3,
+					Active:     true,
+					VideoCodec: "H264",
+					Clients:    []Client{{ID: "1"}, {ID: "2"}, {ID: "3"}},
+				}},
+			},
+			{Name: "other"},
+		},
+	}
+	require.Equal(t, expect, Merge(a, b))
+}
@@ -0,0 +1,28 @@
+package statspb
+
+import (
+	"context"
+
+	"github.com/rfratto/rtmp_exporter/rtmpstats"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// NewServer returns a StatsServiceServer that retrieves stats using get, such
+// as exporter.Exporter.Stats.
+func NewServer(get func(ctx context.Context) (*rtmpstats.Stats, error)) StatsServiceServer {
+	return &server{get: get}
+}
+
+type server struct {
+	UnimplementedStatsServiceServer
+	get func(ctx context.Context) (*rtmpstats.Stats, error)
+}
+
+func (s *server) GetStats(ctx context.Context, _ *GetStatsRequest) (*Stats, error) {
+	stats, err := s.get(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "retrieving stats: %v", err)
+	}
+	return FromStats(stats), nil
+}
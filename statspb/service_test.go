@@ -0,0 +1,68 @@
+package statspb
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/rfratto/rtmp_exporter/rtmpstats"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStatsService(t *testing.T) {
+	var fail bool
+	get := func(ctx context.Context) (*rtmpstats.Stats, error) {
+		if fail {
+			return nil, errors.New("connection refused")
+		}
+
+		f, err := os.Open("../rtmpstats/testdata/stats.xml")
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return rtmpstats.Unmarshal(f)
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := grpc.NewServer()
+	RegisterStatsServiceServer(srv, NewServer(get))
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	cc, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer cc.Close()
+	client := NewStatsServiceClient(cc)
+
+	s, err := client.GetStats(context.Background(), &GetStatsRequest{})
+	require.NoError(t, err)
+
+	require.Equal(t, "1.19.0", s.NginxVersion)
+	require.Equal(t, int64(93879000), s.UptimeMs)
+	require.Equal(t, int64(2338696), s.BitrateIn)
+	require.Len(t, s.Applications, 1)
+	require.Equal(t, "live", s.Applications[0].Name)
+
+	stream := s.Applications[0].Streams[0]
+	require.Equal(t, "streamName", stream.Name)
+	require.Equal(t, int64(2333128), stream.BitrateIn)
+	require.Len(t, stream.Clients, 4)
+	require.Equal(t, "High", stream.VideoProfile)
+	require.Equal(t, 4.0, stream.VideoLevel)
+	require.Len(t, stream.VideoTracks, 1)
+	require.Equal(t, int64(1920), stream.VideoTracks[0].Width)
+	require.Nil(t, stream.Subscribers)
+	require.Nil(t, stream.Hls)
+
+	fail = true
+	_, err = client.GetStats(context.Background(), &GetStatsRequest{})
+	require.Equal(t, codes.Unavailable, status.Code(err))
+}
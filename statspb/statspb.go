@@ -0,0 +1,130 @@
+// Package statspb exposes rtmpstats.Stats over gRPC. The messages and service
+// are generated from stats.proto.
+package statspb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative stats.proto
+
+import (
+	"time"
+
+	"github.com/rfratto/rtmp_exporter/rtmpstats"
+)
+
+// FromStats converts s into its protobuf mirror.
+func FromStats(s *rtmpstats.Stats) *Stats {
+	res := &Stats{
+		NginxVersion:     s.NGINXVersion,
+		NginxRtmpVersion: s.NGINXRTMPVersion,
+		Compiler:         s.Compiler,
+		Pid:              int64(s.PID),
+		UptimeMs:         milliseconds(s.Uptime),
+		Accepted:         int64(s.Accepted),
+		BitrateIn:        s.BitrateIn,
+		BitrateOut:       s.BitrateOut,
+		BytesIn:          s.BytesIn,
+		BytesOut:         s.BytesOut,
+	}
+	if !s.Built.IsZero() {
+		res.BuiltUnixSeconds = s.Built.Unix()
+	}
+	if len(s.DecodeWarnings) > 0 {
+		res.DecodeWarnings = make(map[string]int64, len(s.DecodeWarnings))
+		for element, n := range s.DecodeWarnings {
+			res.DecodeWarnings[element] = int64(n)
+		}
+	}
+
+	for _, app := range s.Applications {
+		pbApp := &Application{Name: app.Name}
+		for _, stream := range app.Streams {
+			pbApp.Streams = append(pbApp.Streams, fromStream(stream))
+		}
+		res.Applications = append(res.Applications, pbApp)
+	}
+	return res
+}
+
+func fromStream(s rtmpstats.Stream) *Stream {
+	res := &Stream{
+		Name:            s.Name,
+		UptimeMs:        milliseconds(s.Uptime),
+		BitrateIn:       s.BitrateIn,
+		BitrateOut:      s.BitrateOut,
+		BytesIn:         s.BytesIn,
+		BytesOut:        s.BytesOut,
+		BitrateVideo:    s.BitrateVideo,
+		BitrateAudio:    s.BitrateAudio,
+		NumClients:      int64(s.NumClients),
+		Publishing:      s.Publishing,
+		Active:          s.Active,
+		VideoWidth:      int64(s.VideoWidth),
+		VideoHeight:     int64(s.VideoHeight),
+		VideoFramerate:  int64(s.VideoFramerate),
+		VideoCodec:      s.VideoCodec,
+		AudioCodec:      s.AudioCodec,
+		AudioChannels:   int64(s.AudioChannels),
+		AudioSampleRate: int64(s.AudioSampleRate),
+		VideoProfile:    s.VideoProfile,
+		VideoCompat:     int64(s.VideoCompat),
+		VideoLevel:      s.VideoLevel,
+		AudioProfile:    s.AudioProfile,
+		Subscribers:     optionalCount(s.Subscribers),
+		Publishers:      optionalCount(s.Publishers),
+	}
+	if s.HLS != nil {
+		res.Hls = &HLS{Fragments: int64(s.HLS.Fragments), Sequence: int64(s.HLS.Sequence)}
+	}
+	if s.DVR != nil {
+		res.Dvr = &DVR{Size: int64(s.DVR.Size)}
+	}
+
+	for _, t := range s.VideoTracks {
+		res.VideoTracks = append(res.VideoTracks, &VideoTrack{
+			Width:     int64(t.Width),
+			Height:    int64(t.Height),
+			Framerate: int64(t.Framerate),
+			Codec:     t.Codec,
+			Profile:   t.Profile,
+			Compat:    int64(t.Compat),
+			Level:     t.Level,
+		})
+	}
+	for _, t := range s.AudioTracks {
+		res.AudioTracks = append(res.AudioTracks, &AudioTrack{
+			Codec:      t.Codec,
+			Profile:    t.Profile,
+			Channels:   int64(t.Channels),
+			SampleRate: int64(t.SampleRate),
+		})
+	}
+
+	for _, c := range s.Clients {
+		res.Clients = append(res.Clients, &Client{
+			Id:            c.ID,
+			Address:       c.Address,
+			UptimeMs:      milliseconds(c.Uptime),
+			FlashVersion:  c.FlashVersion,
+			PageUrl:       c.PageURL,
+			SwfUrl:        c.SWFURL,
+			DroppedFrames: int64(c.DroppedFrames),
+			AvSync:        int64(c.AVSync),
+			TimestampMs:   milliseconds(c.Timestamp),
+			Active:        c.Active,
+			Publishing:    c.Publishing,
+			EntriesCount:  int64(c.EntriesCount),
+		})
+	}
+	return res
+}
+
+func milliseconds(d time.Duration) int64 {
+	return int64(d / time.Millisecond)
+}
+
+func optionalCount(n *int) *int64 {
+	if n == nil {
+		return nil
+	}
+	v := int64(*n)
+	return &v
+}
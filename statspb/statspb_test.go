@@ -0,0 +1,35 @@
+package statspb
+
+import (
+	"testing"
+
+	"github.com/rfratto/rtmp_exporter/rtmpstats"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromStats(t *testing.T) {
+	subscribers := 3
+	s := &rtmpstats.Stats{
+		DecodeWarnings: rtmpstats.DecodeWarnings{"bw_in": 2},
+		Applications: []rtmpstats.Application{{
+			Name: "live",
+			Streams: []rtmpstats.Stream{{
+				Name:        "streamName",
+				Subscribers: &subscribers,
+				AudioTracks: []rtmpstats.AudioTrack{{Codec: "AAC", SampleRate: 44100}},
+				HLS:         &rtmpstats.HLS{Fragments: 5, Sequence: 83},
+				DVR:         &rtmpstats.DVR{Size: 1024},
+			}},
+		}},
+	}
+
+	res := FromStats(s)
+	require.Equal(t, map[string]int64{"bw_in": 2}, res.DecodeWarnings)
+
+	stream := res.Applications[0].Streams[0]
+	require.Equal(t, int64(3), stream.GetSubscribers())
+	require.Nil(t, stream.Publishers)
+	require.Equal(t, int64(44100), stream.AudioTracks[0].SampleRate)
+	require.Equal(t, int64(83), stream.Hls.Sequence)
+	require.Equal(t, int64(1024), stream.Dvr.Size)
+}